@@ -0,0 +1,66 @@
+package metaerr_test
+
+import (
+	stderr "errors"
+	"fmt"
+	"testing"
+
+	"github.com/quantumcycle/metaerr"
+	"github.com/stretchr/testify/assert"
+)
+
+var tenant = metaerr.StringMeta("tenant")
+
+func TestAnnotateMetaError(t *testing.T) {
+	a := assert.New(t)
+
+	err := CreateError("failure", map[string][]string{
+		"errorCode": {"code1"},
+	})
+	annotated := metaerr.Annotate(err, tenant("acme"))
+
+	a.Equal("failure [errorCode=code1] [tenant=acme]", annotated.Error())
+	a.Regexp(fmt.Sprintf(`^failure \[errorCode=code1\] \[tenant=acme\]
+\s+at.+/metaerr/errors_test.go:%d$`, createErrorLocation),
+		fmt.Sprintf("%+v", annotated))
+	// the original error is left untouched
+	a.Equal("failure [errorCode=code1]", err.Error())
+}
+
+func TestAnnotateWrappedMetaError(t *testing.T) {
+	a := assert.New(t)
+
+	err := Wrap(stderr.New("failure"), "wrapped")
+	annotated := metaerr.Annotate(err, tenant("acme"))
+
+	a.Equal("wrapped [tenant=acme]: failure", annotated.Error())
+	a.Equal("wrapped: failure", err.Error())
+	_, isPtr := annotated.(*metaerr.Error)
+	a.True(isPtr)
+}
+
+func TestAnnotateForeignError(t *testing.T) {
+	a := assert.New(t)
+
+	err := fmt.Errorf("context: %w", CreateError("failure", nil))
+	annotated := metaerr.Annotate(err, tenant("acme"))
+	wrapped := Wrap(metaerr.Annotate(annotated, metaerr.StringMeta("user")("123")), "wrapped")
+
+	a.Equal("context: failure [tenant=acme]: failure", annotated.Error())
+	a.Equal("wrapped: context: failure [tenant=acme] [user=123]: failure", wrapped.Error())
+	a.Regexp(fmt.Sprintf(`^wrapped
+\s+at.+/metaerr/errors_test.go:%d
+context: failure \[tenant=acme\] \[user=123\]
+failure
+\s+at.+/metaerr/errors_test.go:%d$`, wrapErrorLocation, createErrorLocation),
+		fmt.Sprintf("%+v", wrapped))
+	a.Equal(map[string][]string{
+		"tenant": {"acme"},
+		"user":   {"123"},
+	}, metaerr.GetMeta(wrapped, true))
+	a.Equal(err, stderr.Unwrap(annotated))
+}
+
+func TestAnnotateNilError(t *testing.T) {
+	assert.Nil(t, metaerr.Annotate(nil, tenant("acme")))
+}
@@ -35,9 +35,13 @@ func (b Builder) New(msg string) error {
 	return New(msg, opts...)
 }
 
+// Newf creates an error formatting its reason like fmt.Errorf. The operands of
+// %w verbs become causes of the error, reachable with errors.Unwrap, errors.Is
+// or errors.As, and their metadata is part of GetMeta(err, true).
 func (b Builder) Newf(format string, args ...any) error {
-	opts := append(b.opts, WithMeta(b.metas...), WithContext(b.context))
-	return New(fmt.Sprintf(format, args...), opts...)
+	reason, wrapped := errorf(format, args...)
+	opts := append(b.opts, WithMeta(b.metas...), WithContext(b.context), withWrapped(nil, wrapped))
+	return New(reason, opts...)
 }
 
 func (b Builder) Wrap(err error, msg string) error {
@@ -45,9 +49,28 @@ func (b Builder) Wrap(err error, msg string) error {
 	return Wrap(err, msg, opts...)
 }
 
+// Wrapf wraps err, formatting the reason like fmt.Errorf. As with Newf, the
+// operands of %w verbs become causes of the error, next to err.
 func (b Builder) Wrapf(err error, format string, args ...any) error {
-	opts := append(b.opts, WithMeta(b.metas...), WithContext(b.context))
-	return Wrap(err, fmt.Sprintf(format, args...), opts...)
+	reason, wrapped := errorf(format, args...)
+	opts := append(b.opts, WithMeta(b.metas...), WithContext(b.context), withWrapped(err, wrapped))
+	return Wrap(err, reason, opts...)
+}
+
+// WrapDeferred is the Builder counterpart of metaerr.WrapDeferred: deferred
+// with a named error result, it wraps the returned error, if any, with msg.
+func (b Builder) WrapDeferred(errp *error, msg string) {
+	if *errp == nil {
+		return
+	}
+	*errp = b.Wrap(*errp, msg)
+}
+
+// errorf formats like fmt.Errorf and returns the message with the operands of
+// the %w verbs.
+func errorf(format string, args ...any) (string, []error) {
+	err := fmt.Errorf(format, args...)
+	return err.Error(), unwrapAll(err)
 }
 
 func NewBuilder(opt ...Option) Builder {
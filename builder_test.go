@@ -3,6 +3,7 @@ package metaerr_test
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/quantumcycle/metaerr"
@@ -98,3 +99,58 @@ func TestBuilderWrapfWithoutErr(t *testing.T) {
 
 	a.Nil(err)
 }
+
+func TestBuilderNewfWithWrappedError(t *testing.T) {
+	a := assert.New(t)
+
+	codeMeta := metaerr.StringMeta("code")
+	tagMeta := metaerr.StringMeta("tags")
+	cause := metaerr.New("no rows", metaerr.WithMeta(codeMeta("x404")))
+	builder := metaerr.NewBuilder().Meta(tagMeta("db"))
+
+	err := builder.Newf("lookup %s: %w", "123", cause)
+
+	a.Equal("lookup 123: no rows [code=x404] [tags=db]", err.Error())
+	a.NotContains(fmt.Sprintf("%+v", err), "%!w")
+	a.Regexp(`^lookup 123: no rows \[code=x404\] \[tags=db\]
+\s+at .+
+no rows \[code=x404\]
+\s+at .+$`, fmt.Sprintf("%+v", err))
+	a.Equal(cause, errors.Unwrap(err))
+	a.Equal(map[string][]string{
+		"code": {"x404"},
+		"tags": {"db"},
+	}, metaerr.GetMeta(err, true))
+}
+
+func TestBuilderNewfWithSeveralWrappedErrors(t *testing.T) {
+	a := assert.New(t)
+
+	cause1 := metaerr.New("failure1", metaerr.WithMeta(metaerr.StringMeta("code")("x01")))
+	cause2 := errors.New("failure2")
+
+	err := metaerr.NewBuilder().Newf("lookup: %w, %w", cause1, cause2)
+
+	a.Equal("lookup: failure1 [code=x01], failure2", err.Error())
+	a.Len(metaerr.FindAll(err), 2)
+	a.True(errors.Is(err, cause2))
+	a.Equal(map[string][]string{
+		"code": {"x01"},
+	}, metaerr.GetMeta(err, true))
+}
+
+func TestBuilderWrapfWithWrappedError(t *testing.T) {
+	a := assert.New(t)
+
+	cause := errors.New("failure")
+	operand := metaerr.New("timeout", metaerr.WithMeta(metaerr.StringMeta("code")("x01")))
+
+	err := metaerr.NewBuilder().Wrapf(cause, "retrying after %w", operand)
+
+	a.Equal("retrying after timeout [code=x01]: failure", err.Error())
+	a.True(errors.Is(err, cause))
+	a.Len(metaerr.FindAll(err), 2)
+	a.Equal(map[string][]string{
+		"code": {"x01"},
+	}, metaerr.GetMeta(err, true))
+}
@@ -0,0 +1,129 @@
+package metaerr
+
+import (
+	"math/rand"
+	"slices"
+	"sync/atomic"
+)
+
+// CapturePolicy decides whether the location of an error, and its stacktrace
+// when WithStackTrace is used, are captured. It is called once the options of
+// the error are applied, so it can look at its metadata. Errors it rejects have
+// no location nor stacktrace, and are printed without them.
+type CapturePolicy func(e Error) bool
+
+// CaptureAlways captures the location of every error. This is the default.
+func CaptureAlways(e Error) bool {
+	return true
+}
+
+// CaptureNever never captures locations, for services only needing metadata.
+func CaptureNever(e Error) bool {
+	return false
+}
+
+// CaptureSampled captures the location of a random share of the errors, rate
+// being between 0 (none) and 1 (all).
+func CaptureSampled(rate float64) CapturePolicy {
+	return func(e Error) bool {
+		return rand.Float64() < rate
+	}
+}
+
+// CaptureIfMeta only captures the location of errors whose chain carries one of
+// the metadata called names. It stops at the first one found.
+func CaptureIfMeta(names ...string) CapturePolicy {
+	return func(e Error) bool {
+		return !walk(e, func(err error) bool {
+			metaErr, ok := asError(err)
+			if !ok {
+				return true
+			}
+			for _, val := range metaErr.metaValues() {
+				if !val.empty() && slices.Contains(names, val.Name) {
+					return false
+				}
+			}
+			return true
+		})
+	}
+}
+
+// WithCapturePolicy sets the capture policy of the error, overriding the one
+// set with SetCapturePolicy. Pass it to NewBuilder to apply it to every error
+// of a Builder.
+func WithCapturePolicy(policy CapturePolicy) Option {
+	return func(e *Error) {
+		e.captureOpts.policy = policy
+	}
+}
+
+// SetCapturePolicy sets the capture policy of every error not created with
+// WithCapturePolicy. nil restores the default, CaptureAlways.
+func SetCapturePolicy(policy CapturePolicy) {
+	if policy == nil {
+		capturePolicy.Store(nil)
+		return
+	}
+	capturePolicy.Store(&policy)
+}
+
+var capturePolicy atomic.Pointer[CapturePolicy]
+
+// captures reports whether the policy of the error accepts capturing its
+// location.
+func (e *Error) captures() bool {
+	policy := e.captureOpts.policy
+	if policy == nil {
+		if global := capturePolicy.Load(); global != nil {
+			policy = *global
+		}
+	}
+	return policy == nil || policy(*e)
+}
+
+// captureOptions are the capture settings of an error, recorded by the options
+// and used once they are all applied. This way options can be given in any
+// order, e.g. WithRootPackageDetector after WithStackTrace.
+type captureOptions struct {
+	locationSkip int
+	stack        bool
+	stackSkip    int
+	stackDepth   int
+	policy       CapturePolicy
+}
+
+// capture captures the location of the error, and its stacktrace with
+// WithStackTrace, once its options are applied, unless its capture policy
+// rejects it.
+func (e *Error) capture() {
+	if e.captures() {
+		e.captureLocation()
+	}
+}
+
+// captureLocation walks the stack once, for both the location and the
+// stacktrace. The location is symbolized right away, the stacktrace when read.
+func (e *Error) captureLocation() {
+	opts := e.captureOpts
+	depth := opts.locationSkip + 1
+	if opts.stack {
+		// +1 because the stacktrace starts after the location
+		depth = max(depth, opts.stackSkip+1+opts.stackDepth)
+	}
+	pcs := callers(depth + maxInternalFrames)
+	// the stacktrace is symbolized later, with the detector of its creation
+	isRoot := rootDetectorOf(e.rootDetector)
+
+	if st := stacktraceOf(pcs, opts.locationSkip, 1, isRoot); len(st.Frames) > 0 {
+		e.setLocation(st.Frames[0])
+	}
+	if opts.stack {
+		e.stack = &lazyStack{
+			pcs:            pcs,
+			frameStackSkip: opts.stackSkip + 1,
+			maxDepth:       opts.stackDepth,
+			isRoot:         isRoot,
+		}
+	}
+}
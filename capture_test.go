@@ -0,0 +1,129 @@
+package metaerr_test
+
+import (
+	stderr "errors"
+	"fmt"
+	"testing"
+
+	"github.com/quantumcycle/metaerr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCaptureNever(t *testing.T) {
+	a := assert.New(t)
+
+	err := metaerr.New("failure", metaerr.WithCapturePolicy(metaerr.CaptureNever), metaerr.WithStackTrace(0, 5))
+	wrapped := metaerr.Wrap(err, "wrapped", metaerr.WithCapturePolicy(metaerr.CaptureNever), metaerr.WithLocationSkip(0))
+
+	merr, ok := metaerr.AsMetaError(wrapped)
+	require.True(t, ok)
+	a.Empty(merr.Location)
+	a.Equal(metaerr.Frame{}, merr.Caller)
+	merr, _ = metaerr.AsMetaError(err)
+	a.Empty(merr.Location)
+	a.Nil(merr.Stacktrace)
+	a.Equal("wrapped\nfailure", fmt.Sprintf("%+v", wrapped))
+
+	data, jsonErr := metaerr.MarshalJSON(wrapped)
+	require.NoError(t, jsonErr)
+	a.JSONEq(`[{"reason":"wrapped"},{"reason":"failure"}]`, string(data))
+}
+
+func TestCaptureIfMeta(t *testing.T) {
+	a := assert.New(t)
+
+	builder := metaerr.NewBuilder(metaerr.WithCapturePolicy(metaerr.CaptureIfMeta("error_code")))
+	errorCode := metaerr.StringMeta("error_code")
+
+	plain, _ := metaerr.AsMetaError(builder.New("failure"))
+	a.Empty(plain.Location)
+	coded, _ := metaerr.AsMetaError(builder.Meta(errorCode("x01")).New("failure"))
+	a.Regexp(`.+/metaerr/capture_test.go:\d+`, coded.Location)
+	// the metadata of the wrapped error counts too
+	wrapped, _ := metaerr.AsMetaError(builder.Wrap(coded, "wrapped"))
+	a.Regexp(`.+/metaerr/capture_test.go:\d+`, wrapped.Location)
+}
+
+func TestCaptureSampled(t *testing.T) {
+	a := assert.New(t)
+
+	none, _ := metaerr.AsMetaError(metaerr.New("failure", metaerr.WithCapturePolicy(metaerr.CaptureSampled(0))))
+	a.Empty(none.Location)
+	all, _ := metaerr.AsMetaError(metaerr.New("failure", metaerr.WithCapturePolicy(metaerr.CaptureSampled(1))))
+	a.Regexp(`.+/metaerr/capture_test.go:\d+`, all.Location)
+}
+
+func TestSetCapturePolicy(t *testing.T) {
+	a := assert.New(t)
+
+	metaerr.SetCapturePolicy(metaerr.CaptureNever)
+	defer metaerr.SetCapturePolicy(nil)
+
+	merr, _ := metaerr.AsMetaError(metaerr.Wrap(stderr.New("io"), "failure"))
+	a.Empty(merr.Location)
+	merr, _ = metaerr.AsMetaError(metaerr.New("failure", metaerr.WithCapturePolicy(metaerr.CaptureAlways)))
+	a.Regexp(`.+/metaerr/capture_test.go:\d+`, merr.Location)
+
+	metaerr.SetCapturePolicy(nil)
+	merr, _ = metaerr.AsMetaError(metaerr.New("failure"))
+	a.Regexp(`.+/metaerr/capture_test.go:\d+`, merr.Location)
+}
+
+func TestFromPanicFollowsCapturePolicy(t *testing.T) {
+	var err error
+	func() {
+		defer metaerr.Recover(&err, metaerr.WithCapturePolicy(metaerr.CaptureNever))
+		panic("boom")
+	}()
+
+	merr, ok := metaerr.AsMetaError(err)
+	require.True(t, ok)
+	assert.Empty(t, merr.Location)
+	assert.Nil(t, merr.Stacktrace)
+}
+
+var benchCaptured error
+
+var benchPolicies = []struct {
+	name   string
+	policy metaerr.CapturePolicy
+}{
+	{"always", metaerr.CaptureAlways},
+	{"never", metaerr.CaptureNever},
+	{"sampled", metaerr.CaptureSampled(0.1)},
+	{"if_meta_found", metaerr.CaptureIfMeta("error_code")},
+	{"if_meta_missing", metaerr.CaptureIfMeta("tenant")},
+}
+
+// BenchmarkNew compares the cost of creating an error with each capture
+// policy.
+func BenchmarkNew(b *testing.B) {
+	errorCode := metaerr.StringMeta("error_code")
+	tag := metaerr.StringMeta("tag")
+	for _, p := range benchPolicies {
+		b.Run(p.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				benchCaptured = metaerr.New("failure", metaerr.WithCapturePolicy(p.policy), metaerr.WithMeta(errorCode("x01"), tag("db")))
+			}
+		})
+	}
+}
+
+// BenchmarkWrap is BenchmarkNew for an error wrapping a chain of three errors,
+// whose metadata CaptureIfMeta looks into.
+func BenchmarkWrap(b *testing.B) {
+	tag := metaerr.StringMeta("tag")
+	chain := metaerr.New("failure", metaerr.WithMeta(metaerr.StringMeta("error_code")("x01"), tag("db")))
+	chain = metaerr.Wrap(chain, "query", metaerr.WithMeta(tag("sql")))
+	chain = metaerr.Wrap(chain, "repository", metaerr.WithMeta(tag("store")))
+	for _, p := range benchPolicies {
+		b.Run(p.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				benchCaptured = metaerr.Wrap(chain, "service", metaerr.WithCapturePolicy(p.policy), metaerr.WithMeta(tag("api")))
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package metaerr
+
+// WrapDeferred wraps *errp with msg when it is not nil. It is meant to be
+// deferred with a named error result, to add context to every error returned
+// by a function:
+//
+//	func loadConfig(path string) (err error) {
+//		defer metaerr.WrapDeferred(&err, "loading config", metaerr.WithMeta(Path(path)))
+//		...
+//	}
+//
+// The error is located in the function that deferred the call, not in a
+// deferred closure. The line depends on how the compiler runs the deferred
+// call: the return statement or the closing brace of the function.
+func WrapDeferred(errp *error, msg string, opt ...Option) {
+	if *errp == nil {
+		return
+	}
+	*errp = Wrap(*errp, msg, opt...)
+}
@@ -0,0 +1,57 @@
+package metaerr_test
+
+import (
+	stderr "errors"
+	"testing"
+
+	"github.com/quantumcycle/metaerr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadConfig(fail bool) (err error) {
+	defer metaerr.WrapDeferred(&err, "loading config", metaerr.WithMeta(metaerr.StringMeta("path")("app.yaml")))
+	if fail {
+		return stderr.New("no such file")
+	}
+	return nil
+}
+
+func loadConfigWithBuilder(fail bool) (err error) {
+	defer metaerr.NewBuilder().Meta(metaerr.StringMeta("path")("app.yaml")).WrapDeferred(&err, "loading config")
+	if fail {
+		return stderr.New("no such file")
+	}
+	return nil
+}
+
+func TestWrapDeferred(t *testing.T) {
+	a := assert.New(t)
+
+	err := loadConfig(true)
+
+	require.Error(t, err)
+	a.Equal("loading config [path=app.yaml]: no such file", err.Error())
+	merr, ok := metaerr.AsMetaError(err)
+	require.True(t, ok)
+	a.Regexp(`.+/metaerr/deferred_test.go:\d+$`, merr.Location)
+	a.Equal("github.com/quantumcycle/metaerr_test.loadConfig", merr.Caller.Function)
+}
+
+func TestWrapDeferredWithoutError(t *testing.T) {
+	assert.NoError(t, loadConfig(false))
+}
+
+func TestBuilderWrapDeferred(t *testing.T) {
+	a := assert.New(t)
+
+	err := loadConfigWithBuilder(true)
+
+	require.Error(t, err)
+	a.Equal("loading config [path=app.yaml]: no such file", err.Error())
+	merr, ok := metaerr.AsMetaError(err)
+	require.True(t, ok)
+	a.Regexp(`.+/metaerr/deferred_test.go:\d+$`, merr.Location)
+	a.Equal("github.com/quantumcycle/metaerr_test.loadConfigWithBuilder", merr.Caller.Function)
+	a.NoError(loadConfigWithBuilder(false))
+}
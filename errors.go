@@ -3,14 +3,13 @@ package metaerr
 import (
 	"bytes"
 	"context"
-	stderr "errors"
 	"fmt"
 	"io"
 	"reflect"
 	"runtime"
 	"slices"
-	"sort"
 	"strings"
+	"sync"
 )
 
 func Wrap(err error, msg string, opt ...Option) error {
@@ -19,9 +18,8 @@ func Wrap(err error, msg string, opt ...Option) error {
 	}
 
 	e := Error{
-		Reason:   msg,
-		Location: getLocation(0, nil),
-		Cause:    err,
+		Reason: msg,
+		Cause:  err,
 	}
 
 	if len(opt) > 0 {
@@ -29,68 +27,242 @@ func Wrap(err error, msg string, opt ...Option) error {
 			o(&e)
 		}
 	}
+	e.finalize()
+	e.capture()
 
 	return &e
 }
 
-func GetMeta(err error, nested bool) map[string][]string {
+// Annotate adds metadata to err without adding a layer to its message. When err
+// is a metaerr error, a copy of it with the additional metadata is returned.
+// Otherwise err is wrapped in a metadata-only layer, without reason nor
+// location, whose metadata is printed on the line of err. Annotate returns nil
+// when err is nil.
+func Annotate(err error, metas ...ErrorMetadata) error {
+	if err == nil {
+		return nil
+	}
+	switch e := err.(type) {
+	case Error:
+		e.Metas = append(slices.Clip(e.Metas), metas...)
+		e.finalize()
+		return e
+	case *Error:
+		annotated := *e
+		annotated.Metas = append(slices.Clip(e.Metas), metas...)
+		annotated.finalize()
+		return &annotated
+	}
+	annotated := Error{
+		Cause: err,
+		Metas: metas,
+	}
+	annotated.finalize()
+	return &annotated
+}
+
+// GetMeta returns the metadata of err. When nested is true, the metadata of
+// every error of the chain is merged, following every branch of multi-cause
+// errors (errors.Join, fmt.Errorf with several %w). Values are sorted and
+// deduplicated unless their definition or opts say otherwise (see MetaOrder).
+// Sensitive metadata has its values replaced by "***", unless opts hold
+// Unredacted. Use MinVisibility to only get the metadata of an audience.
+func GetMeta(err error, nested bool, opts ...ViewOption) map[string][]string {
+	var layers [][]MetaValue
+	walk(err, func(err error) bool {
+		if metaErr, ok := asError(err); ok {
+			layers = append(layers, metaErr.metaValues())
+		}
+		return nested
+	})
+	return viewMeta(layers, newView(opts))
+}
+
+// viewMeta merges the metadata values of the layers of a chain, outermost
+// first, and orders and redacts them as v says.
+func viewMeta(layers [][]MetaValue, v view) map[string][]string {
 	meta := make(map[string][]string)
+	orders := make(map[string]MetaOrder)
+	sensitive := make(map[string]bool)
+	collectMeta(meta, orders, sensitive, layers, v.minVisibility)
+
+	//order all slices to make output deterministic
+	for k, values := range meta {
+		meta[k] = orderValues(values, v.orderOf(orders[k]))
+		if sensitive[k] && !v.unredacted && len(values) > 0 {
+			meta[k] = []string{redactedValue}
+		}
+	}
 
-	for err != nil {
-		if metaErr, ok := AsMetaError(err); ok {
-			for _, m := range metaErr.Metas {
-				values := m(metaErr)
-				for _, val := range values {
-					//ignore empty metadata
-					if len(val.Values) == 0 {
-						continue
-					}
-					if meta[val.Name] == nil {
-						meta[val.Name] = make([]string, 0, len(val.Values))
-					}
-					for _, v := range val.Values {
-						if v != "" {
-							meta[val.Name] = append(meta[val.Name], v)
-						}
-					}
+	return meta
+}
+
+func collectMeta(meta map[string][]string, orders map[string]MetaOrder, sensitive map[string]bool, layers [][]MetaValue, minVisibility Visibility) {
+	// innermost error first, its values were added first
+	for i := len(layers) - 1; i >= 0; i-- {
+		for _, val := range layers[i] {
+			if val.empty() || val.Visibility < minVisibility {
+				continue
+			}
+			orders[val.Name] = val.Order
+			sensitive[val.Name] = sensitive[val.Name] || val.Sensitive
+			if meta[val.Name] == nil {
+				meta[val.Name] = make([]string, 0, len(val.Values))
+			}
+			for _, v := range val.Values {
+				if v != "" {
+					meta[val.Name] = append(meta[val.Name], v)
 				}
 			}
+		}
+	}
+}
 
-			//sort all slices to make output deterministic
-			for k, v := range meta {
-				sort.Strings(v)
-				//remove consecutive duplicates
-				meta[k] = slices.Compact(v)
+// unwrapAll returns the direct causes of err. It supports both Unwrap() error
+// and Unwrap() []error, as implemented by errors.Join and by fmt.Errorf with
+// several %w verbs.
+func unwrapAll(err error) []error {
+	switch u := err.(type) {
+	case interface{ Unwrap() []error }:
+		causes := make([]error, 0, len(u.Unwrap()))
+		for _, cause := range u.Unwrap() {
+			if cause != nil {
+				causes = append(causes, cause)
 			}
 		}
+		return causes
+	case interface{ Unwrap() error }:
+		if cause := u.Unwrap(); cause != nil {
+			return []error{cause}
+		}
+	}
+	return nil
+}
 
-		if !nested {
-			break
+// isJoin reports whether the message of a multi-cause error is only made of
+// the messages of its causes, as with errors.Join. Such an error has nothing
+// to print on its own.
+func isJoin(err error) bool {
+	u, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return false
+	}
+	msgs := make([]string, 0, len(u.Unwrap()))
+	for _, cause := range u.Unwrap() {
+		if cause != nil {
+			msgs = append(msgs, cause.Error())
 		}
-		err = stderr.Unwrap(err)
 	}
+	return err.Error() == strings.Join(msgs, "\n")
+}
 
-	return meta
+// holdsMessages reports whether msg contains the message of every cause.
+func holdsMessages(msg string, causes []error) bool {
+	for _, cause := range causes {
+		if cause != nil && !strings.Contains(msg, cause.Error()) {
+			return false
+		}
+	}
+	return true
 }
 
 type Error struct {
-	Context    context.Context
-	Location   string
-	Reason     string
+	Context  context.Context
+	Location string
+	// Caller is the frame Location points to, with its function and package.
+	// It is the zero Frame when the location was not captured.
+	Caller Frame
+	Reason string
+	// Stacktrace captured by WithStackTrace is symbolized the first time the
+	// error is read: it is set on the errors returned by AsMetaError, Find and
+	// FindAll, and used when the error is printed, logged or encoded. Use
+	// StackFrames on an Error obtained by a type assertion or errors.As.
 	Stacktrace *Stacktrace
 	Cause      error
 	Metas      []ErrorMetadata
+	// kind is the identity of the Sentinel the error was created from, if any.
+	kind *kind
+	// formatted is true when Reason was formatted with %w verbs. Reason then
+	// already holds the message of every cause except lineCause.
+	formatted bool
+	lineCause error
+	// snapshot evaluates Metas once at creation, see WithMetaSnapshot.
+	snapshot bool
+	// captureOpts are the capture settings recorded by the options, used once
+	// they are all applied, see capture.
+	captureOpts captureOptions
+	// stack is the stacktrace captured with WithStackTrace, symbolized into
+	// Stacktrace when the error is read, see resolve.
+	stack *lazyStack
 	// rootDetector classifies whether an import path is a stack-terminating
-	// "root" package (stdlib/runtime). nil means DefaultRootPackage. Set via
-	// WithRootPackageDetector; must be applied before WithLocationSkip /
-	// WithStackTrace to take effect (those capture eagerly).
+	// "root" package (stdlib/runtime). nil means the default detector, see
+	// SetDefaultRootPackageDetector. Set via WithRootPackageDetector.
 	rootDetector func(pkg string) bool
 }
 
+// resolve sets Stacktrace from the stack captured by WithStackTrace, which is
+// symbolized on first use.
+func (e *Error) resolve() {
+	if e.stack != nil {
+		e.Stacktrace = e.stack.stacktrace()
+	}
+}
+
+// StackFrames returns the frames of the stacktrace captured by WithStackTrace,
+// symbolizing them on first use, or nil without stacktrace. Unlike the
+// Stacktrace field, it works on an Error obtained by a type assertion or
+// errors.As, which do not go through AsMetaError.
+func (e Error) StackFrames() []Frame {
+	e.resolve()
+	if e.Stacktrace == nil {
+		return nil
+	}
+	return e.Stacktrace.Frames
+}
+
+// finalize completes the error once all its options are applied.
+func (e *Error) finalize() {
+	if e.snapshot || metaSnapshot.Load() {
+		e.snapshotMetas()
+	}
+}
+
+// snapshotMetas evaluates Metas and replaces them with their static values.
+func (e *Error) snapshotMetas() {
+	if len(e.Metas) == 0 {
+		return
+	}
+	e.Metas = []ErrorMetadata{staticMeta(e.metaValues())}
+}
+
+// metaValues evaluates the metadata of e.
+func (e Error) metaValues() []MetaValue {
+	var values []MetaValue
+	for _, m := range e.Metas {
+		values = append(values, m(e)...)
+	}
+	return values
+}
+
 func (e Error) Unwrap() error {
 	return e.Cause
 }
 
+// Is reports whether target is the Sentinel e was created from, or another
+// error created from the same Sentinel.
+func (e Error) Is(target error) bool {
+	if e.kind == nil {
+		return false
+	}
+	if s, ok := target.(Sentinel); ok {
+		return s.kind == e.kind
+	}
+	if t, ok := AsMetaError(target); ok {
+		return t.kind == e.kind
+	}
+	return false
+}
+
 func (e Error) Error() string {
 	buf := new(bytes.Buffer)
 	e.printError(buf, false)
@@ -98,6 +270,16 @@ func (e Error) Error() string {
 }
 
 func AsMetaError(err error) (Error, bool) {
+	metaError, ok := asError(err)
+	if ok {
+		metaError.resolve()
+	}
+	return metaError, ok
+}
+
+// asError is AsMetaError without symbolizing the stacktrace, for reading the
+// metadata of the errors of a chain.
+func asError(err error) (Error, bool) {
 	if metaError, ok := err.(Error); ok {
 		return metaError, true
 	}
@@ -107,47 +289,78 @@ func AsMetaError(err error) (Error, bool) {
 	return Error{}, false
 }
 
-func (e Error) printError(w io.Writer, withLocation bool) {
-	var err error = e
-	var errWriter errorWriter
-	if withLocation {
-		errWriter = &stackErrorWriter{
-			writer: w,
-		}
-	} else {
-		errWriter = &lineErrorWriter{
-			writer: w,
-		}
+// As lets errors.As fill both Error and *Error targets, whichever of New or
+// Wrap created the error.
+func (e Error) As(target any) bool {
+	e.resolve()
+	switch t := target.(type) {
+	case *Error:
+		*t = e
+		return true
+	case **Error:
+		*t = &e
+		return true
 	}
-	for err != nil {
-		var message string = ""
-		var location string = ""
-		var metaMsg string = ""
-		var st *Stacktrace
+	return false
+}
+
+// Find returns the outermost metaerr error of err's chain. Unlike AsMetaError,
+// it looks behind other wrappers (fmt.Errorf with %w...) and into every branch
+// of multi-cause errors.
+func Find(err error) (Error, bool) {
+	var found Error
+	var ok bool
+	walk(err, func(err error) bool {
+		found, ok = AsMetaError(err)
+		return !ok
+	})
+	return found, ok
+}
 
+// FindAll returns every metaerr error of err's chain, outermost first. Like
+// Find, it looks behind other wrappers and into every branch of multi-cause
+// errors.
+func FindAll(err error) []Error {
+	var all []Error
+	walk(err, func(err error) bool {
 		if metaError, ok := AsMetaError(err); ok {
-			message = metaError.Reason
-			if len(metaError.Metas) > 0 {
-				metasStr := make([]string, 0, len(metaError.Metas))
-				metas := GetMeta(metaError, false)
-				for k, v := range metas {
-					metasStr = append(metasStr, fmt.Sprintf("[%s=%s]", k, strings.Join(v, ",")))
+			all = append(all, metaError)
+		}
+		return true
+	})
+	return all
+}
+
+// walk calls fn for every error of err's chain in depth-first order, following
+// every branch of multi-cause errors. It stops as soon as fn returns false, and
+// reports whether the whole chain was walked.
+func walk(err error, fn func(err error) bool) bool {
+	for err != nil {
+		if !fn(err) {
+			return false
+		}
+		causes := unwrapAll(err)
+		if len(causes) > 1 {
+			for _, cause := range causes {
+				if !walk(cause, fn) {
+					return false
 				}
-				//To make the output deterministic
-				sort.Strings(metasStr)
-				metaMsg = strings.Join(metasStr, " ")
-			}
-			if withLocation && metaError.Location != "" {
-				location = metaError.Location
 			}
-			if withLocation && metaError.Stacktrace != nil {
-				st = metaError.Stacktrace
-			}
-		} else {
-			message = err.Error()
+			return true
+		}
+		err = nil
+		if len(causes) == 1 {
+			err = causes[0]
 		}
-		errWriter.Error(message, metaMsg, location, st)
-		err = stderr.Unwrap(err)
+	}
+	return true
+}
+
+func (e Error) printError(w io.Writer, withLocation bool) {
+	if withLocation {
+		Render(w, e, StackRenderer)
+	} else {
+		Render(w, e, LineRenderer)
 	}
 }
 
@@ -164,8 +377,7 @@ func (e Error) Format(s fmt.State, verb rune) {
 
 func New(reason string, opt ...Option) error {
 	e := Error{
-		Reason:   reason,
-		Location: getLocation(0, nil),
+		Reason: reason,
 	}
 
 	if len(opt) > 0 {
@@ -173,16 +385,19 @@ func New(reason string, opt ...Option) error {
 			o(&e)
 		}
 	}
+	e.finalize()
+	e.capture()
 
 	return e
 }
 
-func getLocation(callerSkip int, isRoot func(pkg string) bool) string {
-	st := newStacktrace(callerSkip, 1, isRoot)
-	if len(st.Frames) == 0 {
-		return ""
+// setLocation sets Caller and Location, which is empty for the zero Frame.
+func (e *Error) setLocation(frame Frame) {
+	e.Caller = frame
+	e.Location = ""
+	if frame.File != "" {
+		e.Location = frame.String()
 	}
-	return st.Frames[0].String()
 }
 
 type Stacktrace struct {
@@ -190,62 +405,152 @@ type Stacktrace struct {
 }
 
 type Frame struct {
-	File string
-	Line int
+	File string `json:"file"`
+	Line int    `json:"line"`
+	// Function is the fully-qualified name of the function, e.g.
+	// "github.com/x/y.(*T).Method", and Package its import path. Both are empty
+	// when unknown.
+	Function string `json:"function,omitempty"`
+	Package  string `json:"package,omitempty"`
 }
 
 func (frame *Frame) String() string {
 	return fmt.Sprintf("%v:%v", frame.File, frame.Line)
 }
 
+// FunctionString formats the frame as "pkg.Func (file:line)", like panics do.
+// It falls back to String when the function is unknown.
+func (frame *Frame) FunctionString() string {
+	if frame.Function == "" {
+		return frame.String()
+	}
+	return fmt.Sprintf("%s (%s)", frame.Function, frame.String())
+}
+
 // Just a struct to be able to get the internal package path of this library to exclude it
 type internal struct{}
 
 var internalPath = reflect.TypeOf(internal{}).PkgPath()
 
-func newStacktrace(frameStackSkip, maxDepth int, isRoot func(pkg string) bool) *Stacktrace {
-	if isRoot == nil {
-		isRoot = DefaultRootPackage
+// Files of this package creating errors. Their frames are skipped to find the
+// location of an error.
+var internalFiles = []string{
+	"/builder.go",
+	"/capture.go",
+	"/deferred.go",
+	"/errors.go",
+	"/options.go",
+	"/panic.go",
+	"/sentinel.go",
+}
+
+func isInternalFile(file string) bool {
+	if !strings.Contains(file, internalPath) {
+		return false
 	}
-	var frames []Frame
-	var index = 0
+	for _, internalFile := range internalFiles {
+		if strings.Contains(file, internalFile) {
+			return true
+		}
+	}
+	return false
+}
+
+// maxInternalFrames is the number of frames captured on top of the requested
+// ones, for the frames of this package and of the panicking runtime.
+const maxInternalFrames = 16
+
+// callers returns the program counters of the calling goroutine, starting with
+// the caller of callers. It walks the stack once, without symbolizing it.
+func callers(depth int) []uintptr {
+	pcs := make([]uintptr, depth)
+	return pcs[:runtime.Callers(2, pcs)]
+}
+
+// frameCache holds the frames of the program counters symbolized so far. A
+// program has a bounded number of call sites, so each one is only symbolized
+// once per process.
+var frameCache sync.Map // uintptr -> []Frame
 
-	// start by skipping everything related to this package
+// framesOf returns the frames of pc, a program counter returned by
+// runtime.Callers. There are several when calls were inlined at pc.
+func framesOf(pc uintptr) []Frame {
+	if frames, ok := frameCache.Load(pc); ok {
+		return frames.([]Frame)
+	}
+	var frames []Frame
+	it := runtime.CallersFrames([]uintptr{pc})
 	for {
-		_, file, _, _ := runtime.Caller(index)
-		if !strings.Contains(file, internalPath) {
+		f, more := it.Next()
+		frames = append(frames, Frame{
+			File:     f.File,
+			Line:     f.Line,
+			Function: f.Function,
+			Package:  packageOf(f.Function),
+		})
+		if !more {
 			break
 		}
-		if !strings.Contains(file, "/builder.go") &&
-			!strings.Contains(file, "/errors.go") &&
-			!strings.Contains(file, "/options.go") {
-			break
+	}
+	frameCache.Store(pc, frames)
+	return frames
+}
+
+// eachFrame calls fn with the frames of pcs, until fn returns false.
+func eachFrame(pcs []uintptr, fn func(frame Frame) bool) {
+	for _, pc := range pcs {
+		for _, frame := range framesOf(pc) {
+			if !fn(frame) {
+				return
+			}
 		}
-		index++
-	}
-
-	// Collect frames up to maxDepth, stopping once we reach the stdlib/runtime
-	// (it won't call back into user code).
-	//
-	// We always keep the FIRST frame and only apply the stdlib check from the
-	// second one on. The first frame is the site that created the error, which is
-	// user code by construction (the stdlib never calls into metaerr). This makes
-	// the worst case graceful: the root classifier can misclassify user code in
-	// a domain-less module (see DefaultRootPackage), and without this guard such
-	// a frame would be dropped, leaving an empty stack / location.
-	for i := index + frameStackSkip; len(frames) < maxDepth; i++ {
-		pc, file, line, ok := runtime.Caller(i)
-		if !ok {
-			break
+	}
+}
+
+// stacktraceOf symbolizes the stack captured as pcs, skipping everything
+// related to this package and then frameStackSkip frames.
+func stacktraceOf(pcs []uintptr, frameStackSkip, maxDepth int, isRoot func(pkg string) bool) *Stacktrace {
+	isRoot = rootDetectorOf(isRoot)
+	var frames []Frame
+	internal := true
+	eachFrame(pcs, func(frame Frame) bool {
+		if internal && isInternalFile(frame.File) {
+			return true
 		}
-		if len(frames) > 0 && isRootFrame(pc, isRoot) {
-			break
+		internal = false
+		if frameStackSkip > 0 {
+			frameStackSkip--
+			return true
 		}
+		return collectFrame(&frames, frame, maxDepth, isRoot)
+	})
 
-		frames = append(frames, Frame{
-			File: file,
-			Line: line,
-		})
+	return &Stacktrace{
+		Frames: frames,
+	}
+}
+
+// newPanicStacktrace captures the stack of a panicking goroutine, from a
+// deferred call. It starts at the frame that panicked, right after
+// runtime.gopanic and the runtime frames raising the panic (runtime.sigpanic,
+// runtime.panicIndex...). It returns nil when the goroutine is not panicking.
+func newPanicStacktrace(maxDepth int, isRoot func(pkg string) bool) *Stacktrace {
+	isRoot = rootDetectorOf(isRoot)
+	var frames []Frame
+	panicking, raising := false, true
+	eachFrame(callers(maxDepth+maxInternalFrames), func(frame Frame) bool {
+		if !panicking {
+			panicking = frame.Function == "runtime.gopanic"
+			return true
+		}
+		if raising && frame.Function != "" && frame.Package == "runtime" {
+			return true
+		}
+		raising = false
+		return collectFrame(&frames, frame, maxDepth, isRoot)
+	})
+	if !panicking || raising {
+		return nil
 	}
 
 	return &Stacktrace{
@@ -253,6 +558,46 @@ func newStacktrace(frameStackSkip, maxDepth int, isRoot func(pkg string) bool) *
 	}
 }
 
+// collectFrame appends frame to frames and reports whether to keep collecting:
+// up to maxDepth, stopping once we reach the stdlib/runtime (it won't call back
+// into user code).
+//
+// We always keep the FIRST frame and only apply the stdlib check from the
+// second one on. The first frame is the site that created the error, which is
+// user code by construction (the stdlib never calls into metaerr). This makes
+// the worst case graceful: the root classifier can misclassify user code in
+// a domain-less module (see DefaultRootPackage), and without this guard such
+// a frame would be dropped, leaving an empty stack / location.
+func collectFrame(frames *[]Frame, frame Frame, maxDepth int, isRoot func(pkg string) bool) bool {
+	if len(*frames) >= maxDepth {
+		return false
+	}
+	if len(*frames) > 0 && isRootFrame(frame, isRoot) {
+		return false
+	}
+	*frames = append(*frames, frame)
+	return len(*frames) < maxDepth
+}
+
+// lazyStack is a stacktrace captured as program counters, and only symbolized
+// the first time it is read. It is shared by the copies of an Error.
+type lazyStack struct {
+	pcs            []uintptr
+	frameStackSkip int
+	maxDepth       int
+	isRoot         func(pkg string) bool
+
+	once sync.Once
+	st   *Stacktrace
+}
+
+func (s *lazyStack) stacktrace() *Stacktrace {
+	s.once.Do(func() {
+		s.st = stacktraceOf(s.pcs, s.frameStackSkip, s.maxDepth, s.isRoot)
+	})
+	return s.st
+}
+
 // packageOf returns the import path of the package a fully-qualified function
 // name belongs to.
 //
@@ -273,17 +618,20 @@ func packageOf(funcName string) string {
 	return funcName
 }
 
-func isRootFrame(pc uintptr, isRoot func(pkg string) bool) bool {
-	fn := runtime.FuncForPC(pc)
-	if fn == nil {
+// isRootFrame classifies a frame by the import path of its function, never by
+// its file path (which -trimpath rewrites). Frames of unknown functions are not
+// roots.
+func isRootFrame(frame Frame, isRoot func(pkg string) bool) bool {
+	if frame.Function == "" {
 		return false
 	}
-	return isRoot(packageOf(fn.Name()))
+	return isRoot(frame.Package)
 }
 
 // DefaultRootPackage is the default root-package classifier, used when none is
-// configured via WithRootPackageDetector. It reports whether an import path
-// belongs to the standard library or runtime — i.e. where stack capture stops.
+// configured via WithRootPackageDetector or SetDefaultRootPackageDetector. It
+// reports whether an import path belongs to the standard library or runtime —
+// i.e. where stack capture stops.
 //
 // Stdlib import paths have no domain (no '.') in their first
 // path segment ("runtime", "net/http", "testing"), whereas conventional module
@@ -396,3 +396,100 @@ func TestErrorTooManyStackSkipReturnsEmptyLocation(t *testing.T) {
 	a.True(ok)
 	a.Equal("", merr.Location)
 }
+
+func TestFormatJoinedErrors(t *testing.T) {
+	a := assert.New(t)
+
+	err1 := CreateError("failure1", map[string][]string{
+		"errorCode": {"code1"},
+	})
+	err2 := stderr.New("failure2")
+	wrapped := Wrap(stderr.Join(err1, err2), "wrapped")
+
+	a.Equal("wrapped: [failure1 [errorCode=code1]; failure2]", wrapped.Error())
+	a.Regexp(fmt.Sprintf(`^wrapped
+\s+at.+/metaerr/errors_test.go:%d
+\t- failure1 \[errorCode=code1\]
+\t  \s+at.+/metaerr/errors_test.go:%d
+\t- failure2$`, wrapErrorLocation, createErrorLocation),
+		fmt.Sprintf("%+v", wrapped))
+}
+
+func TestFormatNestedJoinedErrors(t *testing.T) {
+	a := assert.New(t)
+
+	inner := stderr.Join(stderr.New("failure2"), stderr.New("failure3"))
+	wrapped := Wrap(stderr.Join(stderr.New("failure1"), Wrap(inner, "nested")), "wrapped")
+
+	a.Equal("wrapped: [failure1; nested: [failure2; failure3]]", wrapped.Error())
+	a.Regexp(`^wrapped
+\s+at.+
+\t- failure1
+\t- nested
+\t  \s+at.+
+\t  \t- failure2
+\t  \t- failure3$`,
+		fmt.Sprintf("%+v", wrapped))
+}
+
+func TestFormatErrorfWithMultipleWrappedErrors(t *testing.T) {
+	a := assert.New(t)
+
+	err := fmt.Errorf("context: %w, %w", CreateError("failure1", nil), stderr.New("failure2"))
+	wrapped := Wrap(err, "wrapped")
+
+	a.Equal("wrapped: context: failure1, failure2", wrapped.Error())
+	a.Regexp(`^wrapped
+\s+at.+
+context: failure1, failure2
+\t- failure1
+\t  \s+at.+
+\t- failure2$`, fmt.Sprintf("%+v", wrapped))
+
+	withMeta := fmt.Errorf("context: %w, %w", CreateError("failure1", map[string][]string{
+		"errorCode": {"code1"},
+	}), stderr.New("failure2"))
+	a.Equal("wrapped: context: failure1 [errorCode=code1], failure2", Wrap(withMeta, "wrapped").Error())
+}
+
+func TestGetMetaMergesMetaFromJoinedErrors(t *testing.T) {
+	a := assert.New(t)
+
+	err1 := CreateError("failure1", map[string][]string{
+		"errorCode": {"code1"},
+	})
+	err2 := CreateError("failure2", map[string][]string{
+		"errorCode": {"code2"},
+		"tag":       {"db"},
+	})
+	wrapped := Wrap(fmt.Errorf("context: %w, %w", err1, err2), "wrapped", metaerr.StringMeta("tag")("security"))
+
+	a.Equal(map[string][]string{
+		"errorCode": {"code1", "code2"},
+		"tag":       {"db", "security"},
+	}, metaerr.GetMeta(wrapped, true))
+	a.Equal(map[string][]string{
+		"tag": {"security"},
+	}, metaerr.GetMeta(wrapped, false))
+}
+
+func TestLocationAndFramesHaveFunction(t *testing.T) {
+	a := assert.New(t)
+
+	merr, ok := metaerr.AsMetaError(SimulateCreateFromLibraryWithStackLevel2("failure"))
+	a.True(ok)
+
+	a.Equal(metaerr.Frame{
+		File:     merr.Caller.File,
+		Line:     simulateCreateFromLibraryWithStackLocation,
+		Function: "github.com/quantumcycle/metaerr_test.SimulateCreateFromLibraryWithStack",
+		Package:  "github.com/quantumcycle/metaerr_test",
+	}, merr.Caller)
+	a.Equal(merr.Caller.String(), merr.Location)
+	if a.NotEmpty(merr.Stacktrace.Frames) {
+		frame := merr.Stacktrace.Frames[0]
+		a.Equal(simulateCreateFromLibraryWithStackLevel2Location, frame.Line)
+		a.Equal("github.com/quantumcycle/metaerr_test.SimulateCreateFromLibraryWithStackLevel2", frame.Function)
+		a.Equal("github.com/quantumcycle/metaerr_test", frame.Package)
+	}
+}
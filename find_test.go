@@ -0,0 +1,61 @@
+package metaerr_test
+
+import (
+	stderr "errors"
+	"fmt"
+	"testing"
+
+	"github.com/quantumcycle/metaerr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorsAsWithValueAndPointerTargets(t *testing.T) {
+	a := assert.New(t)
+
+	created := fmt.Errorf("context: %w", metaerr.New("created"))
+	wrapped := fmt.Errorf("context: %w", metaerr.Wrap(stderr.New("failure"), "wrapped"))
+
+	for _, err := range []error{created, wrapped} {
+		var value metaerr.Error
+		require.True(t, stderr.As(err, &value))
+		var pointer *metaerr.Error
+		require.True(t, stderr.As(err, &pointer))
+		a.Equal(value.Reason, pointer.Reason)
+		a.Equal(value.Location, pointer.Location)
+	}
+}
+
+func TestFindLooksBehindStandardWrappers(t *testing.T) {
+	a := assert.New(t)
+
+	err := fmt.Errorf("context: %w", CreateError("failure", nil))
+
+	_, ok := metaerr.AsMetaError(err)
+	a.False(ok)
+	merr, ok := metaerr.Find(err)
+	a.True(ok)
+	a.Equal("failure", merr.Reason)
+
+	_, ok = metaerr.Find(stderr.New("failure"))
+	a.False(ok)
+	_, ok = metaerr.Find(nil)
+	a.False(ok)
+}
+
+func TestFindAllReturnsEveryLayer(t *testing.T) {
+	a := assert.New(t)
+
+	err1 := CreateError("failure1", nil)
+	err2 := Wrap(stderr.New("failure2"), "wrapped2")
+	err := Wrap(fmt.Errorf("context: %w", stderr.Join(err1, err2)), "wrapped")
+
+	all := metaerr.FindAll(err)
+
+	reasons := make([]string, 0, len(all))
+	for _, merr := range all {
+		reasons = append(reasons, merr.Reason)
+	}
+	a.Equal([]string{"wrapped", "failure1", "wrapped2"}, reasons)
+	a.Empty(metaerr.FindAll(stderr.New("failure")))
+}
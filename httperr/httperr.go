@@ -0,0 +1,210 @@
+// Package httperr writes metaerr error chains as RFC 7807 problem details
+// (application/problem+json) HTTP responses.
+//
+// The response is only built from metadata: the status, type, title and detail
+// come from configurable metadata keys, and extension members from a whitelist
+// of metadata or from public metadata. Error reasons and locations are never
+// sent to the client.
+package httperr
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/quantumcycle/metaerr"
+)
+
+// ContentType is the media type of problem details responses.
+const ContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object. Extensions are encoded as
+// additional members, except those named like a standard member.
+type Problem struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Extensions map[string]any
+}
+
+// standardMembers are the members defined by RFC 7807, which extensions cannot
+// override.
+var standardMembers = map[string]bool{
+	"type":     true,
+	"title":    true,
+	"status":   true,
+	"detail":   true,
+	"instance": true,
+}
+
+func (p Problem) MarshalJSON() ([]byte, error) {
+	members := make(map[string]any, len(p.Extensions)+4)
+	for k, v := range p.Extensions {
+		if !standardMembers[k] {
+			members[k] = v
+		}
+	}
+	members["type"] = p.Type
+	members["title"] = p.Title
+	members["status"] = p.Status
+	if p.Detail != "" {
+		members["detail"] = p.Detail
+	}
+	return json.Marshal(members)
+}
+
+// Writer turns error chains into problem details responses. Create it with
+// NewWriter.
+type Writer struct {
+	statusKey     string
+	typeKey       string
+	titleKey      string
+	detailKey     string
+	extensions    []string
+	public        bool
+	defaultStatus int
+}
+
+type Option func(*Writer)
+
+// WithStatusKey sets the metadata holding the HTTP status. Default is
+// "http_status".
+func WithStatusKey(key string) Option {
+	return func(w *Writer) {
+		w.statusKey = key
+	}
+}
+
+// WithTypeKey sets the metadata holding the problem type URI. Default is
+// "problem_type".
+func WithTypeKey(key string) Option {
+	return func(w *Writer) {
+		w.typeKey = key
+	}
+}
+
+// WithTitleKey sets the metadata holding the problem title. Default is
+// "problem_title".
+func WithTitleKey(key string) Option {
+	return func(w *Writer) {
+		w.titleKey = key
+	}
+}
+
+// WithDetailKey sets the metadata holding the problem detail. Default is
+// "problem_detail".
+func WithDetailKey(key string) Option {
+	return func(w *Writer) {
+		w.detailKey = key
+	}
+}
+
+// WithExtensions whitelists metadata exposed as extension members. No
+// metadata is exposed by default.
+func WithExtensions(keys ...string) Option {
+	return func(w *Writer) {
+		w.extensions = append(w.extensions, keys...)
+	}
+}
+
+// WithPublicExtensions exposes every metadata of the chain with public
+// visibility (see metaerr.VisibilityPublic) as extension members, on top of
+// the metadata whitelisted with WithExtensions. The metadata used for the
+// standard members is not repeated.
+func WithPublicExtensions() Option {
+	return func(w *Writer) {
+		w.public = true
+	}
+}
+
+// WithDefaultStatus sets the status used when the error has no valid status
+// metadata. Default is 500.
+func WithDefaultStatus(status int) Option {
+	return func(w *Writer) {
+		w.defaultStatus = status
+	}
+}
+
+func NewWriter(opt ...Option) Writer {
+	w := Writer{
+		statusKey:     "http_status",
+		typeKey:       "problem_type",
+		titleKey:      "problem_title",
+		detailKey:     "problem_detail",
+		defaultStatus: http.StatusInternalServerError,
+	}
+	for _, o := range opt {
+		o(&w)
+	}
+	return w
+}
+
+// Problem builds the problem details of err. Single-valued members use the
+// value of the outermost error of the chain having the metadata. The status
+// must be a 4xx or 5xx code, the title defaults to the status text and the
+// type to "about:blank".
+func (w Writer) Problem(err error) Problem {
+	p := Problem{
+		Type:   "about:blank",
+		Status: w.defaultStatus,
+	}
+	if val, ok := metaerr.OutermostMeta(err, w.statusKey); ok {
+		if status, convErr := strconv.Atoi(val); convErr == nil && status >= 400 && status <= 599 {
+			p.Status = status
+		}
+	}
+	if val, ok := metaerr.OutermostMeta(err, w.typeKey); ok {
+		p.Type = val
+	}
+	p.Title = http.StatusText(p.Status)
+	if val, ok := metaerr.OutermostMeta(err, w.titleKey); ok {
+		p.Title = val
+	}
+	if val, ok := metaerr.OutermostMeta(err, w.detailKey); ok {
+		p.Detail = val
+	}
+
+	if len(w.extensions) > 0 {
+		meta := metaerr.GetMeta(err, true)
+		for _, key := range w.extensions {
+			p.addExtension(key, meta[key])
+		}
+	}
+	if w.public {
+		for key, values := range metaerr.GetMeta(err, true, metaerr.MinVisibility(metaerr.VisibilityPublic)) {
+			if key != w.statusKey && key != w.typeKey && key != w.titleKey && key != w.detailKey {
+				p.addExtension(key, values)
+			}
+		}
+	}
+	return p
+}
+
+func (p *Problem) addExtension(key string, values []string) {
+	if len(values) == 0 {
+		return
+	}
+	if p.Extensions == nil {
+		p.Extensions = make(map[string]any)
+	}
+	if len(values) == 1 {
+		p.Extensions[key] = values[0]
+	} else {
+		p.Extensions[key] = values
+	}
+}
+
+// Write writes the problem details of err to rw.
+func (w Writer) Write(rw http.ResponseWriter, err error) {
+	p := w.Problem(err)
+	body, jsonErr := json.Marshal(p)
+	if jsonErr != nil {
+		http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	rw.Header().Set("Content-Type", ContentType)
+	rw.Header().Set("X-Content-Type-Options", "nosniff")
+	rw.WriteHeader(p.Status)
+	rw.Write(body)
+}
@@ -0,0 +1,137 @@
+package httperr_test
+
+import (
+	"encoding/json"
+	stderr "errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/quantumcycle/metaerr"
+	"github.com/quantumcycle/metaerr/httperr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	status    = metaerr.StringMeta("http_status")
+	errorCode = metaerr.StringMeta("error_code")
+	field     = metaerr.StringsMeta("field")
+	sqlQuery  = metaerr.StringMeta("sql_query")
+	title     = metaerr.StringMeta("problem_title")
+	detail    = metaerr.StringMeta("problem_detail")
+)
+
+func writeProblem(t *testing.T, w httperr.Writer, err error) (*httptest.ResponseRecorder, map[string]any) {
+	rec := httptest.NewRecorder()
+	w.Write(rec, err)
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	return rec, body
+}
+
+func TestWriteProblemFromMetadata(t *testing.T) {
+	a := assert.New(t)
+
+	err := metaerr.New("user 123 not found in shard 4", metaerr.WithMeta(
+		status("404"),
+		title("User not found"),
+		detail("No user with this ID"),
+		errorCode("x404"),
+		sqlQuery("SELECT * FROM users"),
+	))
+	w := httperr.NewWriter(httperr.WithExtensions("error_code"))
+
+	rec, body := writeProblem(t, w, fmt.Errorf("handler: %w", err))
+
+	a.Equal(http.StatusNotFound, rec.Code)
+	a.Equal(httperr.ContentType, rec.Header().Get("Content-Type"))
+	a.Equal(map[string]any{
+		"type":       "about:blank",
+		"title":      "User not found",
+		"status":     float64(404),
+		"detail":     "No user with this ID",
+		"error_code": "x404",
+	}, body)
+	a.NotContains(rec.Body.String(), "shard")
+	a.NotContains(rec.Body.String(), "errors_test.go")
+}
+
+func TestWriteProblemDefaults(t *testing.T) {
+	a := assert.New(t)
+
+	rec, body := writeProblem(t, httperr.NewWriter(), stderr.New("connection refused"))
+
+	a.Equal(http.StatusInternalServerError, rec.Code)
+	a.Equal(map[string]any{
+		"type":   "about:blank",
+		"title":  "Internal Server Error",
+		"status": float64(500),
+	}, body)
+}
+
+func TestWriteProblemWithCustomKeys(t *testing.T) {
+	a := assert.New(t)
+
+	err := metaerr.New("invalid", metaerr.WithMeta(
+		metaerr.StringMeta("status")("422"),
+		metaerr.StringMeta("type")("https://example.com/problems/validation"),
+		field("email", "name"),
+	))
+	w := httperr.NewWriter(
+		httperr.WithStatusKey("status"),
+		httperr.WithTypeKey("type"),
+		httperr.WithExtensions("field", "type"),
+		httperr.WithDefaultStatus(http.StatusBadRequest),
+	)
+
+	rec, body := writeProblem(t, w, err)
+
+	a.Equal(http.StatusUnprocessableEntity, rec.Code)
+	a.Equal("https://example.com/problems/validation", body["type"])
+	a.Equal("Unprocessable Entity", body["title"])
+	a.Equal([]any{"email", "name"}, body["field"])
+}
+
+func TestProblemUsesOutermostStatus(t *testing.T) {
+	err := metaerr.New("not found", metaerr.WithMeta(status("404")))
+	wrapped := metaerr.Wrap(err, "wrapped", metaerr.WithMeta(status("409")))
+
+	assert.Equal(t, http.StatusConflict, httperr.NewWriter().Problem(wrapped).Status)
+}
+
+func TestProblemIgnoresInvalidStatus(t *testing.T) {
+	a := assert.New(t)
+
+	w := httperr.NewWriter(httperr.WithDefaultStatus(http.StatusBadGateway))
+
+	a.Equal(http.StatusBadGateway, w.Problem(metaerr.New("failure", metaerr.WithMeta(status("abc")))).Status)
+	a.Equal(http.StatusBadGateway, w.Problem(metaerr.New("failure", metaerr.WithMeta(status("200")))).Status)
+}
+
+func TestWriteProblemWithPublicExtensions(t *testing.T) {
+	publicStatus := metaerr.StringMeta("http_status", metaerr.WithVisibility(metaerr.VisibilityPublic))
+	publicCode := metaerr.StringMeta("error_code", metaerr.WithVisibility(metaerr.VisibilityPublic))
+	publicField := metaerr.StringsMeta("field", metaerr.WithVisibility(metaerr.VisibilityPublic))
+
+	err := metaerr.New("invalid user", metaerr.WithMeta(
+		publicStatus("400"),
+		publicCode("x400"),
+		publicField("email", "name"),
+		sqlQuery("SELECT * FROM users"),
+	))
+	w := httperr.NewWriter(httperr.WithPublicExtensions())
+
+	rec, body := writeProblem(t, w, err)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, map[string]any{
+		"type":       "about:blank",
+		"title":      "Bad Request",
+		"status":     float64(400),
+		"error_code": "x400",
+		"field":      []any{"email", "name"},
+	}, body)
+}
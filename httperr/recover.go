@@ -0,0 +1,77 @@
+package httperr
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/quantumcycle/metaerr"
+)
+
+// Reporter is told about the error of every recovered panic, e.g. to log it.
+type Reporter func(r *http.Request, err error)
+
+// Responder writes the response for the error of a recovered panic.
+type Responder func(w http.ResponseWriter, r *http.Request, err error)
+
+type requestKey struct{}
+
+// RequestMethod creates metadata from the method of the request a panic was
+// recovered from by Recoverer.
+var RequestMethod = metaerr.StringMetaFromContextFunc("http_method", func(ctx context.Context) (string, bool) {
+	r, ok := ctx.Value(requestKey{}).(*http.Request)
+	if !ok {
+		return "", false
+	}
+	return r.Method, true
+})
+
+// RequestPath creates metadata from the URL path of the request a panic was
+// recovered from by Recoverer.
+var RequestPath = metaerr.StringMetaFromContextFunc("http_path", func(ctx context.Context) (string, bool) {
+	r, ok := ctx.Value(requestKey{}).(*http.Request)
+	if !ok {
+		return "", false
+	}
+	return r.URL.Path, true
+})
+
+// Recoverer returns a middleware recovering the panics of the next handler.
+// The panic is converted with metaerr.FromPanic, so the error is located where
+// the handler panicked. The error gets the request context (see
+// metaerr.WithContext) and the RequestMethod, RequestPath and given metadata,
+// e.g. a request ID read from the context with metaerr.StringMetaFromContextKey.
+//
+// The error is then passed to report, when not nil, and to respond, which
+// defaults to writing a 500 problem details response with NewWriter().
+//
+// Like net/http, Recoverer lets http.ErrAbortHandler panics through.
+func Recoverer(report Reporter, respond Responder, metas ...metaerr.ErrorMetadata) func(http.Handler) http.Handler {
+	if respond == nil {
+		w := NewWriter()
+		respond = func(rw http.ResponseWriter, r *http.Request, err error) {
+			w.Write(rw, err)
+		}
+	}
+	allMetas := append([]metaerr.ErrorMetadata{RequestMethod(), RequestPath()}, metas...)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), requestKey{}, r)
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+				err := metaerr.FromPanic(rec, metaerr.WithContext(ctx), metaerr.WithMeta(allMetas...))
+				if report != nil {
+					report(r, err)
+				}
+				respond(rw, r, err)
+			}()
+			next.ServeHTTP(rw, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package httperr_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/quantumcycle/metaerr"
+	"github.com/quantumcycle/metaerr/httperr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type requestIDKey struct{}
+
+var requestID = metaerr.StringMetaFromContextKey("request_id", requestIDKey{})
+
+func panickingHandler(w http.ResponseWriter, r *http.Request) {
+	panic("boom")
+}
+
+const panicLocation = 21
+
+func TestRecovererReportsAndResponds(t *testing.T) {
+	a := assert.New(t)
+
+	var reported error
+	report := func(r *http.Request, err error) {
+		reported = err
+	}
+	handler := httperr.Recoverer(report, nil, requestID())(http.HandlerFunc(panickingHandler))
+
+	req := httptest.NewRequest(http.MethodPost, "/users/123", nil)
+	req = req.WithContext(context.WithValue(req.Context(), requestIDKey{}, "r-1"))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Error(t, reported)
+	a.Equal("boom [http_method=POST] [http_path=/users/123] [request_id=r-1]", reported.Error())
+	merr, ok := metaerr.AsMetaError(reported)
+	require.True(t, ok)
+	a.Regexp(fmt.Sprintf(`.+/httperr/recover_test.go:%d$`, panicLocation), merr.Location)
+
+	a.Equal(http.StatusInternalServerError, rec.Code)
+	a.Equal(httperr.ContentType, rec.Header().Get("Content-Type"))
+	a.NotContains(rec.Body.String(), "boom")
+}
+
+func TestRecovererWithCustomResponder(t *testing.T) {
+	var responded error
+	respond := func(w http.ResponseWriter, r *http.Request, err error) {
+		responded = err
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	handler := httperr.Recoverer(nil, respond)(http.HandlerFunc(panickingHandler))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.EqualError(t, responded, "boom [http_method=GET] [http_path=/]")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+func TestRecovererLetsAbortHandlerThrough(t *testing.T) {
+	handler := httperr.Recoverer(nil, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
+
+func TestRecovererPassesThroughWithoutPanic(t *testing.T) {
+	handler := httperr.Recoverer(nil, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
@@ -0,0 +1,322 @@
+package metaerr
+
+import (
+	"encoding/json"
+	stderr "errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonLayer is the JSON form of a single error of a chain. Errors that are not
+// metaerr errors only carry their message as Reason and their Go type as Type.
+// Multi-cause errors end the chain and hold one chain per branch in Causes.
+type jsonLayer struct {
+	Reason   string              `json:"reason"`
+	Type     string              `json:"type,omitempty"`
+	Metadata map[string][]string `json:"metadata,omitempty"`
+	// Order holds the MetaOrder of the metadata not sorted by default.
+	Order map[string]MetaOrder `json:"order,omitempty"`
+	// Visibility holds the Visibility of the metadata that are not internal.
+	Visibility map[string]Visibility `json:"visibility,omitempty"`
+	Location   string                `json:"location,omitempty"`
+	Function   string                `json:"function,omitempty"`
+	Stacktrace []Frame               `json:"stacktrace,omitempty"`
+	// Formatted is set when Reason was formatted with %w verbs, and already
+	// holds the messages of the causes, except the first one with LineCause.
+	Formatted bool          `json:"formatted,omitempty"`
+	LineCause bool          `json:"line_cause,omitempty"`
+	Causes    [][]jsonLayer `json:"causes,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler. The error chain is encoded as an array
+// of layers, outermost first. See MarshalJSON for the format.
+func (e Error) MarshalJSON() ([]byte, error) {
+	return MarshalJSON(e)
+}
+
+// MarshalJSON encodes any error chain as a JSON array of layers, outermost
+// first. Each metaerr layer has its reason, metadata, location, the function
+// of its location and stacktrace frames. Other errors in the chain are encoded
+// as plain message layers with their Go type, the same way they are printed by
+// the %+v verb. A multi-cause error (errors.Join, fmt.Errorf with several %w)
+// has a "causes" array holding the layers of each branch.
+//
+//	[
+//	  {"reason":"cannot fetch content","location":".../main.go:12","function":"main.main"},
+//	  {"reason":"failure","metadata":{"error_code":["x01"]},"location":".../main.go:11","function":"main.fetch"},
+//	  {"reason":"connection refused","type":"*errors.errorString"}
+//	]
+func MarshalJSON(err error) ([]byte, error) {
+	if err == nil {
+		return []byte("null"), nil
+	}
+	return json.Marshal(jsonLayers(err))
+}
+
+func jsonLayers(err error) []jsonLayer {
+	var layers []jsonLayer
+	for err != nil {
+		var layer jsonLayer
+		if metaError, ok := AsMetaError(err); ok {
+			layer = jsonLayer{
+				Reason:   metaError.Reason,
+				Location: metaError.Location,
+				Function: metaError.Caller.Function,
+			}
+			// the metadata is evaluated once, it may read the context
+			values := metaError.metaValues()
+			if meta := viewMeta([][]MetaValue{values}, view{}); len(meta) > 0 {
+				layer.Metadata = meta
+				layer.Order = metaOrders(values)
+				layer.Visibility = metaVisibilities(values)
+			}
+			if metaError.Stacktrace != nil {
+				layer.Stacktrace = metaError.Stacktrace.Frames
+			}
+			layer.Formatted = metaError.formatted
+			layer.LineCause = metaError.formatted && metaError.lineCause != nil
+		} else if msgErr, ok := err.(decodedError); ok {
+			layer = jsonLayer{
+				Reason: err.Error(),
+				Type:   msgErr.typeName(),
+			}
+		} else {
+			layer = jsonLayer{
+				Reason: err.Error(),
+				Type:   fmt.Sprintf("%T", err),
+			}
+		}
+
+		causes := unwrapAll(err)
+		if len(causes) > 1 {
+			for _, cause := range causes {
+				layer.Causes = append(layer.Causes, jsonLayers(cause))
+			}
+			return append(layers, layer)
+		}
+		layers = append(layers, layer)
+		err = nil
+		if len(causes) == 1 {
+			err = causes[0]
+		}
+	}
+	return layers
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It rebuilds an error chain
+// encoded by MarshalJSON into e. The outermost layer must be a metaerr layer,
+// use the UnmarshalJSON function for chains that may start with another error.
+// As with other json.Unmarshaler, a JSON null leaves e unchanged.
+func (e *Error) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var layers []jsonLayer
+	if err := json.Unmarshal(data, &layers); err != nil {
+		return err
+	}
+	if len(layers) == 0 {
+		return stderr.New("metaerr: no error layer to decode")
+	}
+	if layers[0].Type != "" {
+		return fmt.Errorf("metaerr: outermost layer is a %s, not a metaerr error", layers[0].Type)
+	}
+	metaError, _ := AsMetaError(decodeLayers(layers))
+	*e = metaError
+	return nil
+}
+
+// UnmarshalJSON rebuilds an error chain encoded by MarshalJSON, typically on
+// the other side of a process boundary. Metaerr layers are decoded as Error with
+// their Reason, Location, Stacktrace and metadata, so GetMeta and %+v work like
+// on the original chain. Since the original ErrorMetadata funcs cannot be
+// transferred, decoded metadata is stored as static values. Other layers are
+// decoded as plain errors printing their original message.
+//
+// A JSON null decodes to a nil chain.
+func UnmarshalJSON(data []byte) (chain error, err error) {
+	var layers []jsonLayer
+	if err := json.Unmarshal(data, &layers); err != nil {
+		return nil, err
+	}
+	return decodeLayers(layers), nil
+}
+
+func decodeLayers(layers []jsonLayer) error {
+	var cause error
+	for i := len(layers) - 1; i >= 0; i-- {
+		layer := layers[i]
+		if len(layer.Causes) > 0 {
+			causes := make([]error, 0, len(layer.Causes))
+			for _, branch := range layer.Causes {
+				if branchErr := decodeLayers(branch); branchErr != nil {
+					causes = append(causes, branchErr)
+				}
+			}
+			cause = &multiMessageError{
+				msg:    layer.Reason,
+				typ:    layer.Type,
+				causes: causes,
+			}
+			continue
+		}
+		if layer.Type != "" {
+			cause = &messageError{
+				msg:   layer.Reason,
+				typ:   layer.Type,
+				cause: cause,
+			}
+			continue
+		}
+		e := Error{
+			Reason:   layer.Reason,
+			Location: layer.Location,
+			Cause:    cause,
+		}
+		if file, line, ok := parseLocation(layer.Location); ok {
+			e.Caller = Frame{
+				File:     file,
+				Line:     line,
+				Function: layer.Function,
+				Package:  packageOf(layer.Function),
+			}
+		}
+		if layer.Formatted {
+			e.formatted = true
+			// the error given to Wrapf is the first cause, see withWrapped
+			if multi, ok := cause.(*multiMessageError); ok && layer.LineCause && len(multi.causes) > 0 {
+				e.lineCause = multi.causes[0]
+			}
+		}
+		if len(layer.Stacktrace) > 0 {
+			e.Stacktrace = &Stacktrace{
+				Frames: layer.Stacktrace,
+			}
+		}
+		if len(layer.Metadata) > 0 {
+			names := make([]string, 0, len(layer.Metadata))
+			for name := range layer.Metadata {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			values := make([]MetaValue, 0, len(names))
+			for _, name := range names {
+				values = append(values, MetaValue{
+					Name:       name,
+					Values:     layer.Metadata[name],
+					Order:      layer.Order[name],
+					Visibility: layer.Visibility[name],
+				})
+			}
+			e.Metas = []ErrorMetadata{staticMeta(values)}
+		}
+		cause = e
+	}
+	return cause
+}
+
+// metaOrders returns the order of the metadata values that are not sorted, the
+// one of the last non-empty definition of a name, as with GetMeta.
+func metaOrders(values []MetaValue) map[string]MetaOrder {
+	orders := make(map[string]MetaOrder)
+	for _, val := range values {
+		if !val.empty() {
+			orders[val.Name] = val.Order
+		}
+	}
+	for name, order := range orders {
+		if order == OrderSorted {
+			delete(orders, name)
+		}
+	}
+	if len(orders) == 0 {
+		return nil
+	}
+	return orders
+}
+
+// metaVisibilities returns the visibility of the metadata values that are not
+// internal. A name whose values have different visibilities gets the lowest
+// one, so that decoding never exposes internal values.
+func metaVisibilities(values []MetaValue) map[string]Visibility {
+	visibilities := make(map[string]Visibility)
+	for _, val := range values {
+		if val.empty() {
+			continue
+		}
+		if current, ok := visibilities[val.Name]; !ok || val.Visibility < current {
+			visibilities[val.Name] = val.Visibility
+		}
+	}
+	for name, visibility := range visibilities {
+		if visibility == VisibilityInternal {
+			delete(visibilities, name)
+		}
+	}
+	if len(visibilities) == 0 {
+		return nil
+	}
+	return visibilities
+}
+
+// parseLocation splits a "file:line" location.
+func parseLocation(location string) (string, int, bool) {
+	sep := strings.LastIndexByte(location, ':')
+	if sep < 0 {
+		return "", 0, false
+	}
+	line, err := strconv.Atoi(location[sep+1:])
+	if err != nil {
+		return "", 0, false
+	}
+	return location[:sep], line, true
+}
+
+// decodedError is implemented by the errors standing for decoded layers that
+// were not metaerr errors.
+type decodedError interface {
+	error
+	typeName() string
+}
+
+// messageError stands for a decoded layer that was not a metaerr error. It
+// keeps the original message and type name so it prints and encodes the same
+// way as the error it was decoded from.
+type messageError struct {
+	msg   string
+	typ   string
+	cause error
+}
+
+func (e *messageError) Error() string {
+	return e.msg
+}
+
+func (e *messageError) Unwrap() error {
+	return e.cause
+}
+
+func (e *messageError) typeName() string {
+	return e.typ
+}
+
+// multiMessageError is the messageError counterpart of a multi-cause error.
+type multiMessageError struct {
+	msg    string
+	typ    string
+	causes []error
+}
+
+func (e *multiMessageError) Error() string {
+	return e.msg
+}
+
+func (e *multiMessageError) Unwrap() []error {
+	return e.causes
+}
+
+func (e *multiMessageError) typeName() string {
+	return e.typ
+}
@@ -0,0 +1,258 @@
+package metaerr_test
+
+import (
+	"encoding/json"
+	stderr "errors"
+	"fmt"
+	"testing"
+
+	"github.com/quantumcycle/metaerr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type jsonLayer struct {
+	Reason     string              `json:"reason"`
+	Type       string              `json:"type"`
+	Metadata   map[string][]string `json:"metadata"`
+	Location   string              `json:"location"`
+	Stacktrace []struct {
+		File string `json:"file"`
+		Line int    `json:"line"`
+	} `json:"stacktrace"`
+}
+
+func decodeLayers(t *testing.T, data []byte) []jsonLayer {
+	var layers []jsonLayer
+	require.NoError(t, json.Unmarshal(data, &layers))
+	return layers
+}
+
+func TestMarshalJSONEncodesEveryLayer(t *testing.T) {
+	a := assert.New(t)
+
+	err := CreateError("failure", map[string][]string{
+		"errorCode": {"code1"},
+	})
+	wrapped := Wrap(err, "wrapped", metaerr.StringMeta("tag")("db"))
+
+	data, jsonErr := json.Marshal(wrapped)
+	require.NoError(t, jsonErr)
+	layers := decodeLayers(t, data)
+
+	require.Len(t, layers, 2)
+	a.Equal("wrapped", layers[0].Reason)
+	a.Equal(map[string][]string{"tag": {"db"}}, layers[0].Metadata)
+	a.Regexp(fmt.Sprintf(`.+/metaerr/errors_test.go:%d`, wrapErrorLocation), layers[0].Location)
+	a.Equal("failure", layers[1].Reason)
+	a.Equal(map[string][]string{"errorCode": {"code1"}}, layers[1].Metadata)
+	a.Regexp(fmt.Sprintf(`.+/metaerr/errors_test.go:%d`, createErrorLocation), layers[1].Location)
+}
+
+func TestMarshalJSONEncodesForeignErrorsAsMessages(t *testing.T) {
+	a := assert.New(t)
+
+	err := fmt.Errorf("context: %w", Wrap(stderr.New("failure"), "wrapped"))
+
+	data, jsonErr := metaerr.MarshalJSON(err)
+	require.NoError(t, jsonErr)
+	layers := decodeLayers(t, data)
+
+	require.Len(t, layers, 3)
+	a.Equal("context: wrapped: failure", layers[0].Reason)
+	a.Equal("*fmt.wrapError", layers[0].Type)
+	a.Equal("wrapped", layers[1].Reason)
+	a.Empty(layers[1].Type)
+	a.Equal("failure", layers[2].Reason)
+	a.Equal("*errors.errorString", layers[2].Type)
+}
+
+func TestMarshalJSONEncodesStacktrace(t *testing.T) {
+	a := assert.New(t)
+
+	err := SimulateCreateFromLibraryWithStackLevel2("failure")
+
+	data, jsonErr := metaerr.MarshalJSON(err)
+	require.NoError(t, jsonErr)
+	layers := decodeLayers(t, data)
+
+	require.Len(t, layers, 1)
+	require.NotEmpty(t, layers[0].Stacktrace)
+	a.Regexp(`.+/metaerr/errors_test.go`, layers[0].Stacktrace[0].File)
+	a.Equal(simulateCreateFromLibraryWithStackLevel2Location, layers[0].Stacktrace[0].Line)
+}
+
+func TestMarshalJSONNilError(t *testing.T) {
+	data, err := metaerr.MarshalJSON(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "null", string(data))
+}
+
+func TestUnmarshalJSONRebuildsChain(t *testing.T) {
+	a := assert.New(t)
+
+	err := CreateError("failure", map[string][]string{
+		"errorCode": {"code2", "code1"},
+	})
+	original := fmt.Errorf("context: %w", Wrap(err, "wrapped", metaerr.StringMeta("tag")("db")))
+
+	data, jsonErr := metaerr.MarshalJSON(original)
+	require.NoError(t, jsonErr)
+	decoded, jsonErr := metaerr.UnmarshalJSON(data)
+	require.NoError(t, jsonErr)
+
+	a.Equal(original.Error(), decoded.Error())
+	a.Equal(fmt.Sprintf("%+v", original), fmt.Sprintf("%+v", decoded))
+	a.Equal(metaerr.GetMeta(original, true), metaerr.GetMeta(decoded, true))
+
+	inner, ok := metaerr.AsMetaError(stderr.Unwrap(decoded))
+	require.True(t, ok)
+	a.Equal("wrapped", inner.Reason)
+	a.Regexp(fmt.Sprintf(`.+/metaerr/errors_test.go:%d`, wrapErrorLocation), inner.Location)
+
+	reencoded, jsonErr := metaerr.MarshalJSON(decoded)
+	require.NoError(t, jsonErr)
+	a.JSONEq(string(data), string(reencoded))
+}
+
+func TestUnmarshalJSONRebuildsStacktrace(t *testing.T) {
+	a := assert.New(t)
+
+	original := SimulateCreateFromLibraryWithStackLevel2("failure")
+	data, err := json.Marshal(original)
+	require.NoError(t, err)
+
+	var decoded metaerr.Error
+	require.NoError(t, json.Unmarshal(data, &decoded))
+
+	a.Equal("failure", decoded.Reason)
+	a.Equal(fmt.Sprintf("%+v", original), fmt.Sprintf("%+v", decoded))
+	require.NotNil(t, decoded.Stacktrace)
+	a.Equal(simulateCreateFromLibraryWithStackLevel2Location, decoded.Stacktrace.Frames[0].Line)
+}
+
+func TestUnmarshalJSONIntoErrorRequiresMetaLayer(t *testing.T) {
+	data, err := metaerr.MarshalJSON(stderr.New("failure"))
+	require.NoError(t, err)
+
+	var decoded metaerr.Error
+	assert.Error(t, json.Unmarshal(data, &decoded))
+	assert.Error(t, json.Unmarshal([]byte("[]"), &decoded))
+}
+
+func TestUnmarshalJSONNull(t *testing.T) {
+	decoded, err := metaerr.UnmarshalJSON([]byte("null"))
+	require.NoError(t, err)
+	assert.Nil(t, decoded)
+}
+
+func TestJSONRoundTripOfJoinedErrors(t *testing.T) {
+	a := assert.New(t)
+
+	err1 := CreateError("failure1", map[string][]string{
+		"errorCode": {"code1"},
+	})
+	original := Wrap(stderr.Join(err1, stderr.New("failure2")), "wrapped")
+
+	data, err := metaerr.MarshalJSON(original)
+	require.NoError(t, err)
+	decoded, err := metaerr.UnmarshalJSON(data)
+	require.NoError(t, err)
+
+	a.Equal(original.Error(), decoded.Error())
+	a.Equal(fmt.Sprintf("%+v", original), fmt.Sprintf("%+v", decoded))
+	a.Equal(metaerr.GetMeta(original, true), metaerr.GetMeta(decoded, true))
+}
+
+func TestJSONRoundTripKeepsFunction(t *testing.T) {
+	err := CreateError("failure", nil)
+
+	data, jsonErr := metaerr.MarshalJSON(err)
+	require.NoError(t, jsonErr)
+	decoded, jsonErr := metaerr.UnmarshalJSON(data)
+	require.NoError(t, jsonErr)
+
+	original, _ := metaerr.AsMetaError(err)
+	merr, ok := metaerr.AsMetaError(decoded)
+	require.True(t, ok)
+	assert.Equal(t, original.Caller, merr.Caller)
+}
+
+func TestJSONRoundTripKeepsFormattedReason(t *testing.T) {
+	a := assert.New(t)
+
+	code := metaerr.StringMeta("code")
+	builder := metaerr.NewBuilder().Meta(code("top"))
+	x := metaerr.New("x", metaerr.WithMeta(code("x")))
+	y := stderr.New("y")
+
+	for _, err := range []error{
+		builder.Newf("a %w and %w", x, y),
+		builder.Newf("a %w", x),
+		builder.Wrapf(x, "b %w", y),
+	} {
+		data, jsonErr := metaerr.MarshalJSON(err)
+		require.NoError(t, jsonErr)
+		decoded, jsonErr := metaerr.UnmarshalJSON(data)
+		require.NoError(t, jsonErr)
+
+		a.Equal(err.Error(), decoded.Error())
+	}
+}
+
+func TestJSONRoundTripKeepsMetaOrder(t *testing.T) {
+	a := assert.New(t)
+
+	code := metaerr.StringMeta("code")
+	attempt := metaerr.StringMeta("attempt", metaerr.WithOrder(metaerr.OrderInsertionWithDuplicates))
+	hop := metaerr.StringMeta("hop", metaerr.WithOrder(metaerr.OrderInsertion))
+	inner := metaerr.New("a", metaerr.WithMeta(code("x"), attempt("2"), hop("db")))
+	err := metaerr.Wrap(inner, "b", metaerr.WithMeta(code("x"), code("a"), attempt("1"), attempt("2"), hop("api"), hop("db")))
+
+	data, jsonErr := metaerr.MarshalJSON(err)
+	require.NoError(t, jsonErr)
+	decoded, jsonErr := metaerr.UnmarshalJSON(data)
+	require.NoError(t, jsonErr)
+
+	a.Equal(map[string][]string{
+		"code":    {"a", "x"},
+		"attempt": {"2", "1", "2"},
+		"hop":     {"db", "api"},
+	}, metaerr.GetMeta(decoded, true))
+	a.Equal(metaerr.GetMeta(err, true), metaerr.GetMeta(decoded, true))
+	a.Equal(err.Error(), decoded.Error())
+}
+
+func TestUnmarshalJSONNullIntoErrorField(t *testing.T) {
+	var payload struct {
+		Err metaerr.Error `json:"err"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"err":null}`), &payload))
+	assert.Equal(t, "", payload.Err.Reason)
+
+	var decoded metaerr.Error
+	require.NoError(t, decoded.UnmarshalJSON([]byte("null")))
+	assert.Equal(t, "", decoded.Reason)
+}
+
+func TestMarshalJSONEvaluatesMetadataOnce(t *testing.T) {
+	a := assert.New(t)
+
+	calls := 0
+	counted := func(err metaerr.Error) []metaerr.MetaValue {
+		calls++
+		return []metaerr.MetaValue{{Name: "request_id", Values: []string{"r1"}, Visibility: metaerr.VisibilityPublic}}
+	}
+	// an empty definition after a non-empty one is ignored, for the order too
+	hop := metaerr.StringsMeta("hop", metaerr.WithOrder(metaerr.OrderInsertion))
+	err := metaerr.New("failure", metaerr.WithMeta(counted, hop("db", "api"), metaerr.StringsMeta("hop")()))
+
+	data, jsonErr := metaerr.MarshalJSON(err)
+	require.NoError(t, jsonErr)
+	a.Equal(1, calls)
+
+	decoded, jsonErr := metaerr.UnmarshalJSON(data)
+	require.NoError(t, jsonErr)
+	a.Equal(metaerr.GetMeta(err, true), metaerr.GetMeta(decoded, true))
+	a.Equal(map[string][]string{"request_id": {"r1"}}, metaerr.GetMeta(decoded, true, metaerr.MinVisibility(metaerr.VisibilityPublic)))
+}
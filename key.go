@@ -0,0 +1,102 @@
+package metaerr
+
+import "fmt"
+
+// Key is a typed metadata key. Metadata created from a Key keeps its typed
+// value, which Get and GetAll return without parsing, while GetMeta and the
+// error message still see it as a string (formatted with fmt.Sprint).
+//
+//	var HTTPStatus = metaerr.NewKey[int]("http_status")
+//
+//	err := metaerr.New("not found", metaerr.WithMeta(HTTPStatus.Meta(404)))
+//	status, ok := metaerr.Get(err, HTTPStatus) // 404, true
+type Key[T any] struct {
+	id *keyID
+}
+
+type keyID struct {
+	name string
+	opts []MetaOption
+}
+
+// NewKey declares a Key for metadata called name. Two keys are distinct even
+// when they share a name.
+func NewKey[T any](name string, opts ...MetaOption) Key[T] {
+	return Key[T]{
+		id: &keyID{
+			name: name,
+			opts: opts,
+		},
+	}
+}
+
+// Name returns the name of the metadata.
+func (k Key[T]) Name() string {
+	return k.id.name
+}
+
+// Meta creates the metadata holding val, to pass to WithMeta or Builder.Meta.
+func (k Key[T]) Meta(val T) ErrorMetadata {
+	return func(err Error) []MetaValue {
+		return withMetaOptions([]MetaValue{
+			{
+				Name:   k.id.name,
+				Values: []string{fmt.Sprint(val)},
+				key:    k.id,
+				typed:  []any{val},
+			},
+		}, k.id.opts)
+	}
+}
+
+// Get returns the value of key set on the outermost error of err's chain
+// having one.
+func Get[T any](err error, key Key[T]) (T, bool) {
+	var found T
+	var ok bool
+	walk(err, func(err error) bool {
+		found, ok = layerValue(err, key)
+		return !ok
+	})
+	return found, ok
+}
+
+// GetAll returns every value of key set in err's chain, outermost first.
+func GetAll[T any](err error, key Key[T]) []T {
+	var all []T
+	walk(err, func(err error) bool {
+		all = append(all, layerValues(err, key)...)
+		return true
+	})
+	return all
+}
+
+func layerValue[T any](err error, key Key[T]) (T, bool) {
+	values := layerValues(err, key)
+	if len(values) == 0 {
+		var zero T
+		return zero, false
+	}
+	return values[0], true
+}
+
+func layerValues[T any](err error, key Key[T]) []T {
+	metaErr, ok := AsMetaError(err)
+	if !ok {
+		return nil
+	}
+	var values []T
+	for _, m := range metaErr.Metas {
+		for _, val := range m(metaErr) {
+			if val.key != key.id {
+				continue
+			}
+			for _, typed := range val.typed {
+				if v, ok := typed.(T); ok {
+					values = append(values, v)
+				}
+			}
+		}
+	}
+	return values
+}
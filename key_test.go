@@ -0,0 +1,74 @@
+package metaerr_test
+
+import (
+	stderr "errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/quantumcycle/metaerr"
+	"github.com/stretchr/testify/assert"
+)
+
+var HTTPStatus = metaerr.NewKey[int]("http_status")
+var RetryAfter = metaerr.NewKey[time.Duration]("retry_after")
+
+func TestKeyGetReturnsTypedValue(t *testing.T) {
+	a := assert.New(t)
+
+	err := metaerr.New("too many requests", metaerr.WithMeta(HTTPStatus.Meta(429), RetryAfter.Meta(2*time.Second)))
+	wrapped := fmt.Errorf("context: %w", Wrap(err, "wrapped"))
+
+	status, ok := metaerr.Get(wrapped, HTTPStatus)
+	a.True(ok)
+	a.Equal(429, status)
+	retryAfter, ok := metaerr.Get(wrapped, RetryAfter)
+	a.True(ok)
+	a.Equal(2*time.Second, retryAfter)
+}
+
+func TestKeyGetReturnsOutermostValue(t *testing.T) {
+	a := assert.New(t)
+
+	err := metaerr.New("not found", metaerr.WithMeta(HTTPStatus.Meta(404)))
+	wrapped := Wrap(err, "wrapped", HTTPStatus.Meta(500))
+
+	status, ok := metaerr.Get(wrapped, HTTPStatus)
+	a.True(ok)
+	a.Equal(500, status)
+	a.Equal([]int{500, 404}, metaerr.GetAll(wrapped, HTTPStatus))
+}
+
+func TestKeyGetWithoutValue(t *testing.T) {
+	a := assert.New(t)
+
+	status, ok := metaerr.Get(metaerr.New("failure"), HTTPStatus)
+	a.False(ok)
+	a.Zero(status)
+	_, ok = metaerr.Get(stderr.New("failure"), HTTPStatus)
+	a.False(ok)
+	a.Empty(metaerr.GetAll(nil, HTTPStatus))
+}
+
+func TestKeysWithSameNameAreDistinct(t *testing.T) {
+	a := assert.New(t)
+
+	otherStatus := metaerr.NewKey[int]("http_status")
+	err := metaerr.New("failure", metaerr.WithMeta(otherStatus.Meta(400)))
+
+	_, ok := metaerr.Get(err, HTTPStatus)
+	a.False(ok)
+	a.Equal("http_status", otherStatus.Name())
+}
+
+func TestKeyMetaIsVisibleAsString(t *testing.T) {
+	a := assert.New(t)
+
+	err := metaerr.New("too many requests", metaerr.WithMeta(HTTPStatus.Meta(429), RetryAfter.Meta(2*time.Second)))
+
+	a.Equal("too many requests [http_status=429] [retry_after=2s]", err.Error())
+	a.Equal(map[string][]string{
+		"http_status": {"429"},
+		"retry_after": {"2s"},
+	}, metaerr.GetMeta(err, false))
+}
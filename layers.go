@@ -0,0 +1,53 @@
+package metaerr
+
+// Layer is one metaerr error of a chain, with its own metadata.
+type Layer struct {
+	Reason     string
+	Location   string
+	Stacktrace *Stacktrace
+	Meta       map[string][]string
+}
+
+// GetLayers returns the metaerr errors of err's chain, outermost first, each
+// with its own metadata. Unlike GetMeta(err, true), which merges the metadata
+// of every layer, it tells which layer set which value. Like FindAll, it looks
+// behind other wrappers and into every branch of multi-cause errors. opts
+// change how metadata is presented, as with GetMeta.
+func GetLayers(err error, opts ...ViewOption) []Layer {
+	metaErrors := FindAll(err)
+	layers := make([]Layer, 0, len(metaErrors))
+	for _, metaError := range metaErrors {
+		layers = append(layers, Layer{
+			Reason:     metaError.Reason,
+			Location:   metaError.Location,
+			Stacktrace: metaError.Stacktrace,
+			Meta:       GetMeta(metaError, false, opts...),
+		})
+	}
+	return layers
+}
+
+// OutermostMeta resolves a single-valued metadata: it returns the value set on
+// the outermost layer of err's chain having it, i.e. the one closest to where
+// the error is handled.
+func OutermostMeta(err error, name string, opts ...ViewOption) (string, bool) {
+	layers := GetLayers(err, opts...)
+	for _, layer := range layers {
+		if values := layer.Meta[name]; len(values) > 0 {
+			return values[0], true
+		}
+	}
+	return "", false
+}
+
+// InnermostMeta resolves a single-valued metadata: it returns the value set on
+// the innermost layer of err's chain having it, i.e. the most specific one.
+func InnermostMeta(err error, name string, opts ...ViewOption) (string, bool) {
+	layers := GetLayers(err, opts...)
+	for i := len(layers) - 1; i >= 0; i-- {
+		if values := layers[i].Meta[name]; len(values) > 0 {
+			return values[0], true
+		}
+	}
+	return "", false
+}
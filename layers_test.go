@@ -0,0 +1,52 @@
+package metaerr_test
+
+import (
+	stderr "errors"
+	"fmt"
+	"testing"
+
+	"github.com/quantumcycle/metaerr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var httpStatus = metaerr.StringMeta("http_status")
+
+func TestGetLayersKeepsMetaPerLayer(t *testing.T) {
+	a := assert.New(t)
+
+	err := CreateError("failure", map[string][]string{
+		"http_status": {"404"},
+		"tag":         {"db"},
+	})
+	wrapped := Wrap(fmt.Errorf("context: %w", err), "wrapped", httpStatus("500"))
+
+	layers := metaerr.GetLayers(wrapped)
+
+	require.Len(t, layers, 2)
+	a.Equal("wrapped", layers[0].Reason)
+	a.Regexp(fmt.Sprintf(`.+/metaerr/errors_test.go:%d`, wrapErrorLocation), layers[0].Location)
+	a.Equal(map[string][]string{"http_status": {"500"}}, layers[0].Meta)
+	a.Equal("failure", layers[1].Reason)
+	a.Regexp(fmt.Sprintf(`.+/metaerr/errors_test.go:%d`, createErrorLocation), layers[1].Location)
+	a.Equal(map[string][]string{"http_status": {"404"}, "tag": {"db"}}, layers[1].Meta)
+}
+
+func TestResolveSingleValuedMeta(t *testing.T) {
+	a := assert.New(t)
+
+	err := metaerr.New("not found", metaerr.WithMeta(httpStatus("404")))
+	wrapped := Wrap(Wrap(err, "wrapped", httpStatus("500")), "wrapped again")
+
+	status, ok := metaerr.InnermostMeta(wrapped, "http_status")
+	a.True(ok)
+	a.Equal("404", status)
+	status, ok = metaerr.OutermostMeta(wrapped, "http_status")
+	a.True(ok)
+	a.Equal("500", status)
+
+	_, ok = metaerr.InnermostMeta(wrapped, "tag")
+	a.False(ok)
+	_, ok = metaerr.OutermostMeta(stderr.New("failure"), "http_status")
+	a.False(ok)
+}
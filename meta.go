@@ -1,74 +1,249 @@
 package metaerr
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+)
 
-func StringMeta(name string) func(string) ErrorMetadata {
+func StringMeta(name string, opts ...MetaOption) func(string) ErrorMetadata {
 	return func(val string) ErrorMetadata {
 		return func(err Error) []MetaValue {
-			return []MetaValue{
+			return withMetaOptions([]MetaValue{
 				{
 					Name:   name,
 					Values: []string{val},
 				},
-			}
+			}, opts)
 		}
 	}
 }
 
-func StringMetaFromContext(name string, ctxKey string) func() ErrorMetadata {
+// StringMetaFromContext creates metadata from the value stored in the error
+// context (see WithContext) under ctxKey. Prefer StringMetaFromContextKey,
+// context keys should not be strings.
+func StringMetaFromContext(name string, ctxKey string, opts ...MetaOption) func() ErrorMetadata {
+	return StringMetaFromContextKey(name, ctxKey, opts...)
+}
+
+// StringMetaFromContextKey creates metadata from the value stored in the error
+// context (see WithContext) under ctxKey, formatted with %v. The key can be of
+// any comparable type, like an unexported key type.
+func StringMetaFromContextKey[K comparable](name string, ctxKey K, opts ...MetaOption) func() ErrorMetadata {
+	return StringMetaFromContextFunc(name, func(ctx context.Context) (string, bool) {
+		val := ctx.Value(ctxKey)
+		if val == nil {
+			return "", false
+		}
+		return fmt.Sprintf("%v", val), true
+	}, opts...)
+}
+
+// StringMetaFromContextFunc creates metadata from the value extract finds in
+// the error context (see WithContext), for values that are not directly stored
+// under a key. extract returns false when there is no value.
+func StringMetaFromContextFunc(name string, extract func(ctx context.Context) (string, bool), opts ...MetaOption) func() ErrorMetadata {
 	return func() ErrorMetadata {
 		return func(err Error) []MetaValue {
 			if err.Context == nil {
 				return nil
 			}
-			val := err.Context.Value(ctxKey)
-			if val == nil {
+			strVal, ok := extract(err.Context)
+			if !ok {
 				return nil
 			}
-			strVal := fmt.Sprintf("%v", val)
-			return []MetaValue{
+			return withMetaOptions([]MetaValue{
 				{
 					Name:   name,
 					Values: []string{strVal},
 				},
+			}, opts)
+		}
+	}
+}
+
+// MetasFromContext creates several metadata from a single lookup in the error
+// context (see WithContext). extract returns the metadata values by name, e.g.
+// the user, tenant and request ID of a request info stored in the context.
+func MetasFromContext(extract func(ctx context.Context) map[string]string, opts ...MetaOption) func() ErrorMetadata {
+	return func() ErrorMetadata {
+		return func(err Error) []MetaValue {
+			if err.Context == nil {
+				return nil
+			}
+			values := extract(err.Context)
+			names := make([]string, 0, len(values))
+			for name := range values {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			metas := make([]MetaValue, 0, len(names))
+			for _, name := range names {
+				metas = append(metas, MetaValue{
+					Name:   name,
+					Values: []string{values[name]},
+				})
 			}
+			return withMetaOptions(metas, opts)
 		}
 	}
 }
 
-func StringsMeta(name string) func(...string) ErrorMetadata {
+func StringsMeta(name string, opts ...MetaOption) func(...string) ErrorMetadata {
 	return func(values ...string) ErrorMetadata {
 		return func(err Error) []MetaValue {
-			return []MetaValue{
+			return withMetaOptions([]MetaValue{
 				{
 					Name:   name,
 					Values: values,
 				},
-			}
+			}, opts)
 		}
 	}
 }
 
-func StringerMeta[T fmt.Stringer](name string) func(T) ErrorMetadata {
+func StringerMeta[T fmt.Stringer](name string, opts ...MetaOption) func(T) ErrorMetadata {
 	return func(val T) ErrorMetadata {
 		return func(err Error) []MetaValue {
 			strVal := val.String()
 			if strVal == "" {
 				return nil
 			}
-			return []MetaValue{
+			return withMetaOptions([]MetaValue{
 				{
 					Name:   name,
 					Values: []string{strVal},
 				},
-			}
+			}, opts)
 		}
 	}
 }
 
+// staticMeta returns an ErrorMetadata always producing the given values. It is
+// used for metadata that has already been evaluated, e.g. decoded from JSON.
+func staticMeta(values []MetaValue) ErrorMetadata {
+	return func(err Error) []MetaValue {
+		return values
+	}
+}
+
 type MetaValue struct {
 	Name   string
 	Values []string
+	// Order is how GetMeta and the error message order the values of the
+	// metadata, see MetaOrder.
+	Order MetaOrder
+	// Sensitive metadata is masked unless read with Unredacted, see Sensitive.
+	Sensitive bool
+	// Visibility is the audience the metadata can be shown to, see
+	// MinVisibility.
+	Visibility Visibility
+	// key and typed hold the typed values of metadata created from a Key
+	key   *keyID
+	typed []any
+}
+
+// empty reports whether the metadata has no value, in which case it is ignored.
+func (v MetaValue) empty() bool {
+	return len(v.Values) == 0
 }
 
 type ErrorMetadata = func(err Error) []MetaValue
+
+// MetaOrder is how the values of a metadata are ordered by GetMeta and in the
+// error message. When the metadata is set on several errors of a chain, the
+// values are merged innermost error first, and the order of the outermost one
+// applies.
+type MetaOrder int
+
+const (
+	// OrderSorted sorts the values and removes duplicates. This is the default.
+	OrderSorted MetaOrder = iota
+	// OrderInsertion keeps the values in the order they were added and
+	// removes duplicates, keeping the first one.
+	OrderInsertion
+	// OrderInsertionWithDuplicates keeps the values in the order they were
+	// added, duplicates included.
+	OrderInsertionWithDuplicates
+)
+
+// orderValues orders values in place according to order.
+func orderValues(values []string, order MetaOrder) []string {
+	switch order {
+	case OrderInsertion:
+		seen := make(map[string]bool, len(values))
+		unique := values[:0]
+		for _, v := range values {
+			if !seen[v] {
+				seen[v] = true
+				unique = append(unique, v)
+			}
+		}
+		return unique
+	case OrderInsertionWithDuplicates:
+		return values
+	default:
+		sort.Strings(values)
+		//remove consecutive duplicates
+		return slices.Compact(values)
+	}
+}
+
+// MetaOption configures the values created by a metadata definition, e.g.
+//
+//	var Attempts = metaerr.StringsMeta("attempt", metaerr.WithOrder(metaerr.OrderInsertionWithDuplicates))
+type MetaOption func(*MetaValue)
+
+// WithOrder sets how the values of the metadata are ordered, see MetaOrder.
+func WithOrder(order MetaOrder) MetaOption {
+	return func(val *MetaValue) {
+		val.Order = order
+	}
+}
+
+// Sensitive marks metadata holding personal or secret data, like emails or
+// account numbers. Its values are masked in the error message and in every
+// export (GetMeta, JSON, slog), unless read with the Unredacted view option.
+// Values of typed keys read with Get or GetAll are not masked.
+func Sensitive() MetaOption {
+	return func(val *MetaValue) {
+		val.Sensitive = true
+	}
+}
+
+// SensitiveStringMeta is StringMeta for sensitive metadata, see Sensitive.
+func SensitiveStringMeta(name string, opts ...MetaOption) func(string) ErrorMetadata {
+	return StringMeta(name, append(slices.Clip(opts), Sensitive())...)
+}
+
+// Visibility is the audience metadata can be shown to. Levels are ordered,
+// from the most restricted to the widest audience.
+type Visibility int
+
+const (
+	// VisibilityInternal metadata is only meant for the service itself: logs,
+	// traces, monitoring. This is the default.
+	VisibilityInternal Visibility = iota
+	// VisibilityPublic metadata is safe to send to API clients, like an error
+	// code or the name of an invalid field.
+	VisibilityPublic
+)
+
+// WithVisibility sets the audience the metadata can be shown to. Use the
+// MinVisibility view option to only read the metadata of an audience.
+func WithVisibility(visibility Visibility) MetaOption {
+	return func(val *MetaValue) {
+		val.Visibility = visibility
+	}
+}
+
+// withMetaOptions applies opts to freshly created values.
+func withMetaOptions(values []MetaValue, opts []MetaOption) []MetaValue {
+	for i := range values {
+		for _, opt := range opts {
+			opt(&values[i])
+		}
+	}
+	return values
+}
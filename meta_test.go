@@ -0,0 +1,130 @@
+package metaerr_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/quantumcycle/metaerr"
+	"github.com/stretchr/testify/assert"
+)
+
+type ctxKey int
+
+const (
+	userKey ctxKey = iota
+	requestInfoKey
+)
+
+type requestInfo struct {
+	user      string
+	tenant    string
+	requestID string
+}
+
+func TestStringMetaFromContextKey(t *testing.T) {
+	a := assert.New(t)
+
+	userFromCtx := metaerr.StringMetaFromContextKey("user", userKey)
+	ctx := context.WithValue(context.Background(), userKey, 123)
+
+	err := metaerr.New("failure", metaerr.WithContext(ctx), metaerr.WithMeta(userFromCtx()))
+	a.Equal(map[string][]string{
+		"user": {"123"},
+	}, metaerr.GetMeta(err, false))
+
+	err = metaerr.New("failure", metaerr.WithContext(context.Background()), metaerr.WithMeta(userFromCtx()))
+	a.Equal(map[string][]string{}, metaerr.GetMeta(err, false))
+}
+
+func TestStringMetaFromContextFunc(t *testing.T) {
+	a := assert.New(t)
+
+	tenantFromCtx := metaerr.StringMetaFromContextFunc("tenant", func(ctx context.Context) (string, bool) {
+		info, ok := ctx.Value(requestInfoKey).(requestInfo)
+		return info.tenant, ok
+	})
+	ctx := context.WithValue(context.Background(), requestInfoKey, requestInfo{tenant: "acme"})
+
+	err := metaerr.New("failure", metaerr.WithContext(ctx), metaerr.WithMeta(tenantFromCtx()))
+	a.Equal(map[string][]string{
+		"tenant": {"acme"},
+	}, metaerr.GetMeta(err, false))
+
+	err = metaerr.New("failure", metaerr.WithMeta(tenantFromCtx()))
+	a.Equal(map[string][]string{}, metaerr.GetMeta(err, false))
+}
+
+func TestMetasFromContext(t *testing.T) {
+	a := assert.New(t)
+
+	requestMetas := metaerr.MetasFromContext(func(ctx context.Context) map[string]string {
+		info, ok := ctx.Value(requestInfoKey).(requestInfo)
+		if !ok {
+			return nil
+		}
+		return map[string]string{
+			"user":       info.user,
+			"tenant":     info.tenant,
+			"request_id": info.requestID,
+		}
+	})
+	ctx := context.WithValue(context.Background(), requestInfoKey, requestInfo{
+		user:      "123",
+		tenant:    "acme",
+		requestID: "r-1",
+	})
+
+	err := metaerr.NewBuilder().Meta(requestMetas()).Context(ctx).New("failure")
+
+	a.Equal("failure [request_id=r-1] [tenant=acme] [user=123]", err.Error())
+	a.Equal(map[string][]string{}, metaerr.GetMeta(metaerr.New("failure", metaerr.WithMeta(requestMetas())), false))
+}
+
+func TestMetaOrderDefaultsToSorted(t *testing.T) {
+	attempts := metaerr.StringsMeta("attempt")
+
+	err := metaerr.New("failure", metaerr.WithMeta(attempts("3", "1", "3", "2")))
+
+	assert.Equal(t, map[string][]string{
+		"attempt": {"1", "2", "3"},
+	}, metaerr.GetMeta(err, false))
+}
+
+func TestMetaOrderInsertion(t *testing.T) {
+	a := assert.New(t)
+
+	hop := metaerr.StringMeta("hop", metaerr.WithOrder(metaerr.OrderInsertion))
+	attempts := metaerr.StringsMeta("attempt", metaerr.WithOrder(metaerr.OrderInsertionWithDuplicates))
+
+	err := metaerr.New("failure", metaerr.WithMeta(hop("storage"), attempts("3", "1", "3")))
+	err = metaerr.Wrap(err, "wrapped", metaerr.WithMeta(hop("billing"), hop("storage"), attempts("2")))
+	err = metaerr.Wrap(err, "wrapped again", metaerr.WithMeta(hop("api")))
+
+	a.Equal(map[string][]string{
+		"hop":     {"storage", "billing", "api"},
+		"attempt": {"3", "1", "3", "2"},
+	}, metaerr.GetMeta(err, true))
+	a.Equal("wrapped again [hop=api]: wrapped [attempt=2] [hop=billing,storage]: failure [attempt=3,1,3] [hop=storage]", err.Error())
+}
+
+func TestOverrideOrder(t *testing.T) {
+	a := assert.New(t)
+
+	hop := metaerr.StringMeta("hop", metaerr.WithOrder(metaerr.OrderInsertion))
+	err := metaerr.Wrap(metaerr.New("failure", metaerr.WithMeta(hop("storage"))), "wrapped", metaerr.WithMeta(hop("billing")))
+
+	a.Equal(map[string][]string{
+		"hop": {"billing", "storage"},
+	}, metaerr.GetMeta(err, true, metaerr.OverrideOrder(metaerr.OrderSorted)))
+
+	tags := metaerr.StringsMeta("tag")
+	err = metaerr.New("failure", metaerr.WithMeta(tags("b", "a", "b")))
+
+	a.Equal(map[string][]string{
+		"tag": {"b", "a", "b"},
+	}, metaerr.GetMeta(err, false, metaerr.OverrideOrder(metaerr.OrderInsertionWithDuplicates)))
+	buf := new(bytes.Buffer)
+	metaerr.Render(buf, err, metaerr.LineRenderer, metaerr.OverrideOrder(metaerr.OrderInsertion))
+	a.Equal("failure [tag=b,a]", buf.String())
+}
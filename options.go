@@ -1,27 +1,36 @@
 package metaerr
 
-import "context"
+import (
+	"context"
+	stderr "errors"
+	"sync/atomic"
+)
 
 type Option func(*Error)
 
+// WithLocationSkip locates the error additionalCallerSkip frames above the
+// function creating it, e.g. 1 for the caller of an error factory.
 func WithLocationSkip(additionalCallerSkip int) Option {
 	return func(e *Error) {
-		//+1 since this is called from the option
-		e.Location = getLocation(additionalCallerSkip, e.rootDetector)
+		e.captureOpts.locationSkip = additionalCallerSkip
 	}
 }
 
+// WithStackTrace captures up to maxDepth frames after the location of the
+// error, skipping additionalCallerSkip more frames.
 func WithStackTrace(additionalCallerSkip, maxDepth int) Option {
 	return func(e *Error) {
-		//+1 because we always skip the first frame since it will be the same as the location
-		e.Stacktrace = newStacktrace(additionalCallerSkip+1, maxDepth, e.rootDetector)
+		e.captureOpts.stack = true
+		e.captureOpts.stackSkip = additionalCallerSkip
+		e.captureOpts.stackDepth = maxDepth
 	}
 }
 
 // WithRootPackageDetector overrides how stack capture decides a frame's package
 // is a stack-terminating "root" (standard library / runtime). isRoot receives an
 // import path (e.g. "net/http", "github.com/x/y") and returns true to stop the
-// walk there. When unset, DefaultRootPackage is used.
+// walk there. When unset, the detector set with SetDefaultRootPackageDetector
+// is used, DefaultRootPackage by default.
 //
 // Use it for projects whose module path has no domain (e.g. `go mod init myapp`),
 // which DefaultRootPackage would otherwise mistake for the standard library:
@@ -33,8 +42,8 @@ func WithStackTrace(additionalCallerSkip, maxDepth int) Option {
 //		return metaerr.DefaultRootPackage(pkg)
 //	})
 //
-// Capture is eager, so this option must be applied BEFORE WithLocationSkip /
-// WithStackTrace. A Builder applies its options in order, so list it first.
+// Like every option, it can be given in any order: the location and the
+// stacktrace are captured once all the options are applied.
 func WithRootPackageDetector(isRoot func(pkg string) bool) Option {
 	return func(e *Error) {
 		e.rootDetector = isRoot
@@ -47,8 +56,48 @@ func WithContext(ctx context.Context) Option {
 	}
 }
 
+// WithMetaSnapshot evaluates the metadata of the error once, when it is
+// created, instead of every time it is read or printed. Values read from the
+// context then stay stable after the context is gone, and hot logging paths
+// avoid evaluating them again. Pass it to NewBuilder to snapshot the metadata
+// of every error of a Builder, or use SetMetaSnapshot for every error.
+func WithMetaSnapshot() Option {
+	return func(e *Error) {
+		e.snapshot = true
+	}
+}
+
+// SetMetaSnapshot sets whether the metadata of every error is evaluated once at
+// creation, as with WithMetaSnapshot. It is disabled by default.
+func SetMetaSnapshot(enabled bool) {
+	metaSnapshot.Store(enabled)
+}
+
+var metaSnapshot atomic.Bool
+
 func WithMeta(metas ...ErrorMetadata) Option {
 	return func(e *Error) {
 		e.Metas = append(e.Metas, metas...)
 	}
 }
+
+// withWrapped makes the %w operands of a formatted reason causes of the error,
+// next to lineCause, the error given to Wrapf (nil for Newf).
+func withWrapped(lineCause error, wrapped []error) Option {
+	return func(e *Error) {
+		if len(wrapped) == 0 {
+			return
+		}
+		causes := wrapped
+		if lineCause != nil {
+			causes = append([]error{lineCause}, wrapped...)
+		}
+		if len(causes) == 1 {
+			e.Cause = causes[0]
+		} else {
+			e.Cause = stderr.Join(causes...)
+		}
+		e.formatted = true
+		e.lineCause = lineCause
+	}
+}
@@ -0,0 +1,63 @@
+package metaerr
+
+import "fmt"
+
+// panicStackDepth is the maximum number of frames captured for a panic.
+const panicStackDepth = 32
+
+// FromPanic converts a value recovered from a panic into an error. An error
+// value is wrapped with the "panic" reason, any other value becomes the reason.
+//
+// It must be called from the deferred function, while the goroutine is still
+// panicking: the location and stacktrace are then the ones of the panic site,
+// not of the deferred call, whatever the options.
+//
+//	defer func() {
+//		if r := recover(); r != nil {
+//			err = metaerr.FromPanic(r, metaerr.WithMeta(Tag("worker")))
+//		}
+//	}()
+func FromPanic(recovered any, opt ...Option) error {
+	e := Error{
+		Reason: fmt.Sprint(recovered),
+	}
+	if err, ok := recovered.(error); ok {
+		e = Error{
+			Reason: "panic",
+			Cause:  err,
+		}
+	}
+
+	for _, o := range opt {
+		o(&e)
+	}
+	e.finalize()
+	if !e.captures() {
+		return e
+	}
+
+	st := newPanicStacktrace(panicStackDepth, e.rootDetector)
+	if st == nil || len(st.Frames) == 0 {
+		e.captureLocation()
+		return e
+	}
+	e.setLocation(st.Frames[0])
+	e.Stacktrace = &Stacktrace{
+		Frames: st.Frames[1:],
+	}
+	return e
+}
+
+// Recover recovers a panic of the calling goroutine and stores it in *errp as
+// an error created by FromPanic, located at the panic site. It must be deferred
+// directly, usually with a named error result:
+//
+//	func work() (err error) {
+//		defer metaerr.Recover(&err, metaerr.WithMeta(Tag("worker")))
+//		...
+//	}
+func Recover(errp *error, opt ...Option) {
+	if r := recover(); r != nil {
+		*errp = FromPanic(r, opt...)
+	}
+}
@@ -0,0 +1,107 @@
+package metaerr_test
+
+import (
+	stderr "errors"
+	"fmt"
+	"testing"
+
+	"github.com/quantumcycle/metaerr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func recoverFrom(f func(), opt ...metaerr.Option) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = metaerr.FromPanic(r, opt...)
+		}
+	}()
+	f()
+	return nil
+}
+
+func panicWithValue() {
+	panic("boom")
+}
+
+func panicWithNilPointer() {
+	var m *metaerr.Error
+	_ = m.Reason
+}
+
+const panicWithValueLocation = 24
+const panicWithNilPointerLocation = 29
+
+func TestFromPanicWithValue(t *testing.T) {
+	a := assert.New(t)
+
+	err := recoverFrom(panicWithValue, metaerr.WithMeta(metaerr.StringMeta("tag")("worker")))
+
+	require.Error(t, err)
+	a.Equal("boom [tag=worker]", err.Error())
+	merr, ok := metaerr.AsMetaError(err)
+	require.True(t, ok)
+	a.Regexp(fmt.Sprintf(`.+/metaerr/panic_test.go:%d$`, panicWithValueLocation), merr.Location)
+	require.NotNil(t, merr.Stacktrace)
+	require.NotEmpty(t, merr.Stacktrace.Frames)
+	a.Regexp(`.+/metaerr/panic_test.go$`, merr.Stacktrace.Frames[0].File)
+}
+
+func TestFromPanicWithRuntimeError(t *testing.T) {
+	a := assert.New(t)
+
+	err := recoverFrom(panicWithNilPointer)
+
+	require.Error(t, err)
+	a.Regexp(`^panic: runtime error: invalid memory address`, err.Error())
+	var runtimeErr interface{ RuntimeError() }
+	a.True(stderr.As(err, &runtimeErr))
+	merr, ok := metaerr.AsMetaError(err)
+	require.True(t, ok)
+	a.Regexp(fmt.Sprintf(`.+/metaerr/panic_test.go:%d$`, panicWithNilPointerLocation), merr.Location)
+}
+
+func TestFromPanicOutsidePanic(t *testing.T) {
+	err := metaerr.FromPanic("not panicking")
+
+	merr, ok := metaerr.AsMetaError(err)
+	require.True(t, ok)
+	assert.Regexp(t, `.+/metaerr/panic_test.go:\d+$`, merr.Location)
+	assert.Nil(t, merr.Stacktrace)
+}
+
+func recoverWithHelper(f func()) (err error) {
+	defer metaerr.Recover(&err, metaerr.WithMeta(metaerr.StringMeta("tag")("worker")))
+	f()
+	return nil
+}
+
+func TestRecover(t *testing.T) {
+	a := assert.New(t)
+
+	err := recoverWithHelper(panicWithValue)
+
+	require.Error(t, err)
+	a.Equal("boom [tag=worker]", err.Error())
+	merr, ok := metaerr.AsMetaError(err)
+	require.True(t, ok)
+	a.Regexp(fmt.Sprintf(`.+/metaerr/panic_test.go:%d$`, panicWithValueLocation), merr.Location)
+}
+
+func TestRecoverWrapsPanickedErrors(t *testing.T) {
+	a := assert.New(t)
+
+	cause := stderr.New("failure")
+	err := recoverWithHelper(func() {
+		panic(cause)
+	})
+
+	a.Equal("panic [tag=worker]: failure", err.Error())
+	a.True(stderr.Is(err, cause))
+}
+
+func TestRecoverWithoutPanic(t *testing.T) {
+	err := recoverWithHelper(func() {})
+
+	assert.NoError(t, err)
+}
@@ -0,0 +1,72 @@
+package metaerr
+
+import (
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// SetDefaultRootPackageDetector sets the root-package classifier of every error
+// not created with WithRootPackageDetector, e.g. ModuleRootPackage for a module
+// declared without a domain. nil restores DefaultRootPackage.
+func SetDefaultRootPackageDetector(isRoot func(pkg string) bool) {
+	if isRoot == nil {
+		defaultRootDetector.Store(nil)
+		return
+	}
+	defaultRootDetector.Store(&isRoot)
+}
+
+var defaultRootDetector atomic.Pointer[func(pkg string) bool]
+
+// rootDetectorOf returns isRoot, or the default detector when it is nil.
+func rootDetectorOf(isRoot func(pkg string) bool) func(pkg string) bool {
+	if isRoot != nil {
+		return isRoot
+	}
+	if detector := defaultRootDetector.Load(); detector != nil {
+		return *detector
+	}
+	return DefaultRootPackage
+}
+
+// ModuleRootPackage is a root-package classifier aware of the modules of the
+// program, read from its build information: the packages of the main module
+// and of its dependencies are never roots, whatever their path, so modules
+// declared without a domain (`go mod init myapp`) need no setup. Other packages
+// are classified by DefaultRootPackage, which is also used alone when the
+// build information is not available.
+//
+//	metaerr.SetDefaultRootPackageDetector(metaerr.ModuleRootPackage)
+func ModuleRootPackage(pkg string) bool {
+	return buildModulesDetector()(pkg)
+}
+
+var buildModulesDetector = sync.OnceValue(func() func(pkg string) bool {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return DefaultRootPackage
+	}
+	modules := []string{info.Main.Path}
+	for _, dep := range info.Deps {
+		modules = append(modules, dep.Path)
+	}
+	return ModulesRootPackage(modules...)
+})
+
+// ModulesRootPackage returns a root-package classifier for which the packages
+// of modules are never roots. Other packages are classified by
+// DefaultRootPackage. See ModuleRootPackage for the modules of the program.
+func ModulesRootPackage(modules ...string) func(pkg string) bool {
+	return func(pkg string) bool {
+		// external test packages are part of the module of the package tested
+		pkg = strings.TrimSuffix(pkg, "_test")
+		for _, module := range modules {
+			if module != "" && (pkg == module || strings.HasPrefix(pkg, module+"/")) {
+				return false
+			}
+		}
+		return DefaultRootPackage(pkg)
+	}
+}
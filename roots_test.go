@@ -41,3 +41,77 @@ func TestDefaultRootPackageComposable(t *testing.T) {
 	assert.False(t, metaerr.DefaultRootPackage("github.com/x/y"))
 	assert.False(t, metaerr.DefaultRootPackage("main"))
 }
+
+func TestOptionOrderDoesNotMatter(t *testing.T) {
+	allRoot := func(string) bool { return true }
+	stackOf := func(err error) *metaerr.Stacktrace {
+		me, _ := metaerr.AsMetaError(err)
+		return me.Stacktrace
+	}
+
+	before := stackOf(metaerr.New("boom", metaerr.WithRootPackageDetector(allRoot), metaerr.WithStackTrace(0, 10)))
+	after := stackOf(metaerr.New("boom", metaerr.WithStackTrace(0, 10), metaerr.WithRootPackageDetector(allRoot)))
+	require.NotNil(t, after)
+	assert.Len(t, after.Frames, 1)
+	assert.Equal(t, before, after)
+
+	noneRoot := func(string) bool { return false }
+	builder := metaerr.NewBuilder(metaerr.WithStackTrace(0, 10), metaerr.WithRootPackageDetector(noneRoot))
+	layered := stackOf(builder.Wrap(metaerr.New("boom"), "wrapped"))
+	require.NotNil(t, layered)
+	assert.Greater(t, len(layered.Frames), 1)
+	layered = stackOf(metaerr.Wrap(metaerr.New("boom"), "wrapped", metaerr.WithStackTrace(0, 10), metaerr.WithLocationSkip(0), metaerr.WithRootPackageDetector(allRoot)))
+	require.NotNil(t, layered)
+	assert.Len(t, layered.Frames, 1)
+}
+
+func TestWithLocationSkipUsesDetectorGivenAfter(t *testing.T) {
+	var seen []string
+	detector := func(pkg string) bool {
+		seen = append(seen, pkg)
+		return metaerr.DefaultRootPackage(pkg)
+	}
+
+	err := metaerr.New("boom", metaerr.WithLocationSkip(0), metaerr.WithStackTrace(0, 10), metaerr.WithRootPackageDetector(detector))
+	assert.NotEmpty(t, err.(metaerr.Error).StackFrames())
+
+	assert.NotEmpty(t, seen, "the detector must be used even when given after the capture options")
+}
+
+func TestModulesRootPackage(t *testing.T) {
+	isRoot := metaerr.ModulesRootPackage("myapp", "example.com/lib")
+
+	assert.False(t, isRoot("myapp"))
+	assert.False(t, isRoot("myapp/internal/store"))
+	assert.False(t, isRoot("myapp_test"))
+	assert.False(t, isRoot("example.com/lib/sub"))
+	assert.False(t, isRoot("github.com/x/y"), "other modules are classified by DefaultRootPackage")
+	assert.True(t, isRoot("myapplication"))
+	assert.True(t, isRoot("net/http"))
+}
+
+func TestModuleRootPackageReadsBuildInfo(t *testing.T) {
+	// the main module and its dependencies
+	assert.False(t, metaerr.ModuleRootPackage("github.com/quantumcycle/metaerr"))
+	assert.False(t, metaerr.ModuleRootPackage("github.com/quantumcycle/metaerr_test"))
+	assert.False(t, metaerr.ModuleRootPackage("github.com/stretchr/testify/assert"))
+	assert.True(t, metaerr.ModuleRootPackage("testing"))
+}
+
+func TestSetDefaultRootPackageDetector(t *testing.T) {
+	noneRoot := func(string) bool { return false }
+	metaerr.SetDefaultRootPackageDetector(noneRoot)
+	defer metaerr.SetDefaultRootPackageDetector(nil)
+
+	err := metaerr.New("boom", metaerr.WithStackTrace(0, 10))
+	metaerr.SetDefaultRootPackageDetector(nil)
+
+	// the stack is symbolized when read, with the detector of its creation
+	me, _ := metaerr.AsMetaError(err)
+	require.NotNil(t, me.Stacktrace)
+	assert.Greater(t, len(me.Stacktrace.Frames), 1)
+
+	// WithRootPackageDetector takes precedence
+	metaerr.SetDefaultRootPackageDetector(noneRoot)
+	assert.Len(t, captureWith(func(string) bool { return true }).Frames, 1)
+}
@@ -0,0 +1,68 @@
+package metaerr_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/quantumcycle/metaerr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	email   = metaerr.SensitiveStringMeta("email")
+	account = metaerr.StringsMeta("account", metaerr.Sensitive())
+	errCode = metaerr.StringMeta("errorCode")
+)
+
+func TestSensitiveMetaIsMaskedInMessage(t *testing.T) {
+	a := assert.New(t)
+
+	err := metaerr.New("failure", metaerr.WithMeta(email("jane@example.com"), errCode("x01")))
+	err = metaerr.Wrap(err, "wrapped", metaerr.WithMeta(account("FR76 1234", "FR76 5678")))
+
+	a.Equal("wrapped [account=***]: failure [email=***] [errorCode=x01]", err.Error())
+	a.NotContains(fmt.Sprintf("%+v", err), "jane@example.com")
+}
+
+func TestSensitiveMetaIsRedactedInExports(t *testing.T) {
+	a := assert.New(t)
+
+	err := metaerr.New("failure", metaerr.WithMeta(email("jane@example.com"), errCode("x01")))
+
+	a.Equal(map[string][]string{
+		"email":     {"***"},
+		"errorCode": {"x01"},
+	}, metaerr.GetMeta(err, true))
+
+	data, jsonErr := metaerr.MarshalJSON(err)
+	require.NoError(t, jsonErr)
+	a.NotContains(string(data), "jane@example.com")
+
+	buf := new(bytes.Buffer)
+	slog.New(slog.NewJSONHandler(buf, nil)).Error("request failed", "err", err)
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	a.Equal("***", entry["err"].(map[string]any)["email"])
+}
+
+func TestUnredactedRevealsSensitiveMeta(t *testing.T) {
+	a := assert.New(t)
+
+	err := metaerr.Wrap(metaerr.New("failure", metaerr.WithMeta(email("jane@example.com"))), "wrapped", metaerr.WithMeta(email("john@example.com")))
+
+	a.Equal(map[string][]string{
+		"email": {"jane@example.com", "john@example.com"},
+	}, metaerr.GetMeta(err, true, metaerr.Unredacted()))
+
+	buf := new(bytes.Buffer)
+	metaerr.Render(buf, err, metaerr.LineRenderer, metaerr.Unredacted())
+	a.Equal("wrapped [email=john@example.com]: failure [email=jane@example.com]", buf.String())
+
+	value, ok := metaerr.InnermostMeta(err, "email", metaerr.Unredacted())
+	a.True(ok)
+	a.Equal("jane@example.com", value)
+}
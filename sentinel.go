@@ -0,0 +1,57 @@
+package metaerr
+
+// Sentinel declares a kind of error once, usually as a package variable.
+// Errors created from a Sentinel get their own location and metadata, and
+// still match the Sentinel with errors.Is, even once wrapped.
+//
+//	var ErrNotFound = metaerr.NewSentinel("not found", metaerr.WithMeta(ErrorCode("x404")))
+//
+//	err := ErrNotFound.New(metaerr.WithMeta(ProductID(id)))
+//	errors.Is(fmt.Errorf("fetching product: %w", err), ErrNotFound) // true
+type Sentinel struct {
+	kind *kind
+}
+
+type kind struct {
+	reason string
+	opts   []Option
+}
+
+// NewSentinel declares a Sentinel with the given reason. The options are
+// applied to every error created from it, before the options given at creation.
+func NewSentinel(reason string, opt ...Option) Sentinel {
+	return Sentinel{
+		kind: &kind{
+			reason: reason,
+			opts:   opt,
+		},
+	}
+}
+
+func (s Sentinel) Error() string {
+	return s.kind.reason
+}
+
+// New creates an error of this kind, located where New is called.
+func (s Sentinel) New(opt ...Option) error {
+	return New(s.kind.reason, s.options(opt)...)
+}
+
+// Wrap creates an error of this kind wrapping err, located where Wrap is
+// called. Like metaerr.Wrap, it returns nil when err is nil.
+func (s Sentinel) Wrap(err error, opt ...Option) error {
+	return Wrap(err, s.kind.reason, s.options(opt)...)
+}
+
+func (s Sentinel) options(opt []Option) []Option {
+	opts := make([]Option, 0, len(s.kind.opts)+len(opt)+1)
+	opts = append(opts, withKind(s.kind))
+	opts = append(opts, s.kind.opts...)
+	return append(opts, opt...)
+}
+
+func withKind(k *kind) Option {
+	return func(e *Error) {
+		e.kind = k
+	}
+}
@@ -0,0 +1,58 @@
+package metaerr_test
+
+import (
+	stderr "errors"
+	"fmt"
+	"testing"
+
+	"github.com/quantumcycle/metaerr"
+	"github.com/stretchr/testify/assert"
+)
+
+var ErrNotFound = metaerr.NewSentinel("not found", metaerr.WithMeta(metaerr.StringMeta("errorCode")("x404")))
+var ErrConflict = metaerr.NewSentinel("conflict")
+
+func TestSentinelMatchesWithErrorsIs(t *testing.T) {
+	a := assert.New(t)
+
+	err := ErrNotFound.New(metaerr.WithMeta(metaerr.StringMeta("product")("123")))
+	wrapped := fmt.Errorf("fetching product: %w", Wrap(err, "wrapped"))
+
+	a.True(stderr.Is(err, ErrNotFound))
+	a.True(stderr.Is(wrapped, ErrNotFound))
+	a.False(stderr.Is(wrapped, ErrConflict))
+	a.False(stderr.Is(metaerr.New("not found"), ErrNotFound))
+}
+
+func TestSentinelInstancesMatchEachOther(t *testing.T) {
+	a := assert.New(t)
+
+	err1 := ErrNotFound.New()
+	err2 := ErrNotFound.Wrap(stderr.New("no rows"))
+
+	a.True(stderr.Is(err2, err1))
+	a.False(stderr.Is(err1, ErrConflict.New()))
+}
+
+func TestSentinelErrorHasOwnLocationAndMeta(t *testing.T) {
+	a := assert.New(t)
+
+	err := ErrNotFound.New(metaerr.WithMeta(metaerr.StringMeta("product")("123")))
+
+	a.Equal("not found [errorCode=x404] [product=123]", err.Error())
+	a.Equal("not found", ErrNotFound.Error())
+	merr, ok := metaerr.AsMetaError(err)
+	a.True(ok)
+	a.Regexp(`.+/metaerr/sentinel_test.go:\d+`, merr.Location)
+}
+
+func TestSentinelWrap(t *testing.T) {
+	a := assert.New(t)
+
+	cause := stderr.New("no rows")
+	err := ErrNotFound.Wrap(cause)
+
+	a.Equal("not found [errorCode=x404]: no rows", err.Error())
+	a.True(stderr.Is(err, cause))
+	a.Nil(ErrNotFound.Wrap(nil))
+}
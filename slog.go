@@ -0,0 +1,124 @@
+package metaerr
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+)
+
+// LogValue implements slog.LogValuer. The error is logged as a group with its
+// message, location, stacktrace and every metadata of the chain as attributes.
+//
+//	slog.Error("request failed", "err", err)
+//	// level=ERROR msg="request failed" err.msg="failure [error_code=x01]" err.location=.../main.go:11 err.error_code=x01
+func (e Error) LogValue() slog.Value {
+	e.resolve()
+	attrs := []slog.Attr{
+		slog.String("msg", e.Error()),
+	}
+	if e.Location != "" {
+		attrs = append(attrs, slog.String("location", e.Location))
+	}
+	if e.Stacktrace != nil && len(e.Stacktrace.Frames) > 0 {
+		stack := make([]string, 0, len(e.Stacktrace.Frames))
+		for _, frame := range e.Stacktrace.Frames {
+			stack = append(stack, frame.String())
+		}
+		attrs = append(attrs, slog.Any("stack", stack))
+	}
+	attrs = append(attrs, metaAttrs(GetMeta(e, true))...)
+	return slog.GroupValue(attrs...)
+}
+
+// metaAttrs converts metadata to attributes sorted by name. Single values are
+// logged as strings, multiple values as a slice.
+func metaAttrs(meta map[string][]string) []slog.Attr {
+	names := make([]string, 0, len(meta))
+	for name := range meta {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	attrs := make([]slog.Attr, 0, len(names))
+	for _, name := range names {
+		values := meta[name]
+		if len(values) == 1 {
+			attrs = append(attrs, slog.String(name, values[0]))
+		} else {
+			attrs = append(attrs, slog.Any(name, values))
+		}
+	}
+	return attrs
+}
+
+// SlogHandler is a slog.Handler that finds errors in a record's attributes and
+// lifts the metadata of their chain to top-level attributes, so log based
+// alerting rules can match on keys like error_code. The record is then passed
+// to the wrapped handler.
+//
+// Attributes added after a WithGroup call end up in that group, lifted
+// metadata included.
+type SlogHandler struct {
+	next slog.Handler
+}
+
+// NewSlogHandler returns a SlogHandler passing records to next.
+func NewSlogHandler(next slog.Handler) *SlogHandler {
+	return &SlogHandler{
+		next: next,
+	}
+}
+
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	var lifted []slog.Attr
+	r.Attrs(func(a slog.Attr) bool {
+		lifted = append(lifted, liftMeta(a)...)
+		return true
+	})
+	if len(lifted) > 0 {
+		r = r.Clone()
+		r.AddAttrs(lifted...)
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var lifted []slog.Attr
+	for _, a := range attrs {
+		lifted = append(lifted, liftMeta(a)...)
+	}
+	if len(lifted) > 0 {
+		attrs = append(attrs[:len(attrs):len(attrs)], lifted...)
+	}
+	return &SlogHandler{
+		next: h.next.WithAttrs(attrs),
+	}
+}
+
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	return &SlogHandler{
+		next: h.next.WithGroup(name),
+	}
+}
+
+// liftMeta returns the metadata attributes of the error held by a, looking
+// into groups.
+func liftMeta(a slog.Attr) []slog.Attr {
+	switch a.Value.Kind() {
+	case slog.KindAny, slog.KindLogValuer:
+		if err, ok := a.Value.Any().(error); ok {
+			return metaAttrs(GetMeta(err, true))
+		}
+	case slog.KindGroup:
+		var lifted []slog.Attr
+		for _, ga := range a.Value.Group() {
+			lifted = append(lifted, liftMeta(ga)...)
+		}
+		return lifted
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package metaerr_test
+
+import (
+	"bytes"
+	"encoding/json"
+	stderr "errors"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/quantumcycle/metaerr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func logJSON(t *testing.T, handler func(slog.Handler) slog.Handler, args ...any) map[string]any {
+	buf := new(bytes.Buffer)
+	var h slog.Handler = slog.NewJSONHandler(buf, nil)
+	if handler != nil {
+		h = handler(h)
+	}
+	slog.New(h).Error("request failed", args...)
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	return entry
+}
+
+func TestErrorLogValue(t *testing.T) {
+	a := assert.New(t)
+
+	err := CreateError("failure", map[string][]string{
+		"errorCode": {"code1"},
+		"tag":       {"db", "security"},
+	})
+
+	entry := logJSON(t, nil, "err", Wrap(err, "wrapped"))
+
+	group, ok := entry["err"].(map[string]any)
+	require.True(t, ok)
+	a.Equal("wrapped: failure [errorCode=code1] [tag=db,security]", group["msg"])
+	a.Regexp(fmt.Sprintf(`.+/metaerr/errors_test.go:%d`, wrapErrorLocation), group["location"])
+	a.Equal("code1", group["errorCode"])
+	a.Equal([]any{"db", "security"}, group["tag"])
+	a.NotContains(group, "stack")
+}
+
+func TestErrorLogValueWithStacktrace(t *testing.T) {
+	entry := logJSON(t, nil, "err", SimulateCreateFromLibraryWithStackLevel2("failure"))
+
+	group, ok := entry["err"].(map[string]any)
+	require.True(t, ok)
+	stack, ok := group["stack"].([]any)
+	require.True(t, ok)
+	require.NotEmpty(t, stack)
+	assert.Regexp(t, fmt.Sprintf(`.+/metaerr/errors_test.go:%d`, simulateCreateFromLibraryWithStackLevel2Location), stack[0])
+}
+
+func TestSlogHandlerLiftsMetadata(t *testing.T) {
+	a := assert.New(t)
+
+	err := CreateError("failure", map[string][]string{
+		"error_code": {"x01"},
+	})
+	handler := func(h slog.Handler) slog.Handler { return metaerr.NewSlogHandler(h) }
+
+	entry := logJSON(t, handler, "err", fmt.Errorf("context: %w", err), "user", "123")
+
+	a.Equal("x01", entry["error_code"])
+	a.Equal("context: failure [error_code=x01]", entry["err"])
+	a.Equal("123", entry["user"])
+}
+
+func TestSlogHandlerLiftsMetadataFromWithAttrsAndGroups(t *testing.T) {
+	a := assert.New(t)
+
+	err := CreateError("failure", map[string][]string{
+		"error_code": {"x01"},
+	})
+	buf := new(bytes.Buffer)
+	logger := slog.New(metaerr.NewSlogHandler(slog.NewJSONHandler(buf, nil)))
+
+	logger.With("err", err).Error("request failed", slog.Group("req", "cause", Wrap(stderr.New("io"), "read", metaerr.StringMeta("tag")("db"))))
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	a.Equal("x01", entry["error_code"])
+	a.Equal("db", entry["tag"])
+}
+
+func TestSlogHandlerIgnoresPlainErrors(t *testing.T) {
+	handler := func(h slog.Handler) slog.Handler { return metaerr.NewSlogHandler(h) }
+
+	entry := logJSON(t, handler, "err", stderr.New("failure"))
+
+	assert.Equal(t, "failure", entry["err"])
+	assert.Len(t, entry, 4)
+}
@@ -0,0 +1,88 @@
+package metaerr_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/quantumcycle/metaerr"
+	"github.com/stretchr/testify/assert"
+)
+
+type sessionKey struct{}
+
+type session struct {
+	user string
+}
+
+// countingUserMeta reads the user of the session in the context, counting its
+// evaluations.
+func countingUserMeta(count *int) func() metaerr.ErrorMetadata {
+	return metaerr.StringMetaFromContextFunc("user", func(ctx context.Context) (string, bool) {
+		*count++
+		s, ok := ctx.Value(sessionKey{}).(*session)
+		if !ok {
+			return "", false
+		}
+		return s.user, true
+	})
+}
+
+func TestErrorMetaIsEvaluatedOnRead(t *testing.T) {
+	a := assert.New(t)
+
+	var count int
+	s := &session{user: "123"}
+	ctx := context.WithValue(context.Background(), sessionKey{}, s)
+	err := metaerr.New("failure", metaerr.WithContext(ctx), metaerr.WithMeta(countingUserMeta(&count)()))
+
+	s.user = "456"
+	a.Equal("failure [user=456]", err.Error())
+	a.Equal(1, count)
+}
+
+func TestWithMetaSnapshot(t *testing.T) {
+	a := assert.New(t)
+
+	var count int
+	s := &session{user: "123"}
+	ctx := context.WithValue(context.Background(), sessionKey{}, s)
+	err := metaerr.New("failure", metaerr.WithMeta(countingUserMeta(&count)()), metaerr.WithMetaSnapshot(), metaerr.WithContext(ctx))
+
+	s.user = "456"
+	a.Equal("failure [user=123]", err.Error())
+	a.Equal(map[string][]string{"user": {"123"}}, metaerr.GetMeta(err, false))
+	a.Equal(1, count)
+}
+
+func TestBuilderWithMetaSnapshot(t *testing.T) {
+	a := assert.New(t)
+
+	var count int
+	s := &session{user: "123"}
+	ctx := context.WithValue(context.Background(), sessionKey{}, s)
+	builder := metaerr.NewBuilder(metaerr.WithMetaSnapshot()).Meta(countingUserMeta(&count)())
+
+	err := builder.Context(ctx).Wrap(metaerr.New("failure"), "wrapped")
+
+	s.user = "456"
+	a.Equal("wrapped [user=123]: failure", err.Error())
+	a.Equal("wrapped [user=123]: failure", err.Error())
+	a.Equal(1, count)
+}
+
+func TestSetMetaSnapshot(t *testing.T) {
+	a := assert.New(t)
+
+	metaerr.SetMetaSnapshot(true)
+	defer metaerr.SetMetaSnapshot(false)
+
+	var count int
+	s := &session{user: "123"}
+	ctx := context.WithValue(context.Background(), sessionKey{}, s)
+	err := metaerr.New("failure", metaerr.WithContext(ctx), metaerr.WithMeta(countingUserMeta(&count)()))
+	annotated := metaerr.Annotate(err, metaerr.StringMeta("tag")("db"))
+
+	s.user = "456"
+	a.Equal("failure [tag=db] [user=123]", annotated.Error())
+	a.Equal(1, count)
+}
@@ -63,20 +63,47 @@ func aUserFuncForStackTest() {}
 // which -trimpath defeats), only on the import-path-based symbol name.
 func TestIsRootFrameClassifiesByPackage(t *testing.T) {
 	stdlibPC := reflect.ValueOf(fmt.Sprintf).Pointer()
-	if !isRootFrame(stdlibPC, DefaultRootPackage) {
+	if !isRootFrame(framesOf(stdlibPC)[0], DefaultRootPackage) {
 		t.Errorf("fmt.Sprintf must be classified as a root frame (name=%q)",
 			runtime.FuncForPC(stdlibPC).Name())
 	}
 
 	userPC := reflect.ValueOf(aUserFuncForStackTest).Pointer()
-	if isRootFrame(userPC, DefaultRootPackage) {
+	if isRootFrame(framesOf(userPC)[0], DefaultRootPackage) {
 		t.Errorf("a user-package func must not be a root frame (name=%q)",
 			runtime.FuncForPC(userPC).Name())
 	}
 }
 
 func TestIsRootFrameNilFunc(t *testing.T) {
-	if isRootFrame(0, DefaultRootPackage) {
-		t.Error("zero PC (FuncForPC == nil) must not be a root frame")
+	if isRootFrame(framesOf(0)[0], DefaultRootPackage) {
+		t.Error("zero PC (unknown function) must not be a root frame")
+	}
+}
+
+func TestStackIsSymbolizedOnFirstRead(t *testing.T) {
+	err := New("boom", WithStackTrace(0, 5)).(Error)
+	if err.stack.st != nil {
+		t.Fatal("the stack must not be symbolized when the error is created")
+	}
+
+	me, _ := AsMetaError(err)
+	if me.Stacktrace == nil || len(me.Stacktrace.Frames) == 0 {
+		t.Fatal("the stack must be symbolized when the error is read")
+	}
+	again, _ := AsMetaError(err)
+	if again.Stacktrace != me.Stacktrace {
+		t.Error("the symbolized stack must be cached")
+	}
+	if frames := err.StackFrames(); len(frames) == 0 || &frames[0] != &me.Stacktrace.Frames[0] {
+		t.Error("StackFrames must return the cached stack")
+	}
+}
+
+func TestFramesOfIsCached(t *testing.T) {
+	pc := reflect.ValueOf(aUserFuncForStackTest).Pointer()
+	frames := framesOf(pc)
+	if cached, ok := frameCache.Load(pc); !ok || &cached.([]Frame)[0] != &frames[0] {
+		t.Error("the frames of a program counter must be cached")
 	}
 }
@@ -0,0 +1,60 @@
+package metaerr_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/quantumcycle/metaerr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackFrames(t *testing.T) {
+	a := assert.New(t)
+
+	created := metaerr.New("boom", metaerr.WithStackTrace(0, 10))
+	frames := created.(metaerr.Error).StackFrames()
+	require.NotEmpty(t, frames)
+	me, _ := metaerr.AsMetaError(created)
+	a.Equal(me.Stacktrace.Frames, frames)
+
+	wrapped := metaerr.Wrap(created, "wrapped", metaerr.WithStackTrace(0, 10))
+	a.NotEmpty(wrapped.(*metaerr.Error).StackFrames())
+
+	var target metaerr.Error
+	require.True(t, errors.As(created, &target))
+	a.Equal(frames, target.StackFrames())
+	var ptrTarget *metaerr.Error
+	require.True(t, errors.As(wrapped, &ptrTarget))
+	a.NotEmpty(ptrTarget.StackFrames())
+
+	a.Nil(metaerr.New("boom").(metaerr.Error).StackFrames())
+}
+
+var benchErr error
+
+// BenchmarkNewWithStackTrace compares errors thrown away, whose stack is never
+// symbolized, with errors whose stack is read or printed.
+func BenchmarkNewWithStackTrace(b *testing.B) {
+	b.Run("discarded", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			benchErr = metaerr.New("failure", metaerr.WithStackTrace(0, 10))
+		}
+	})
+	b.Run("read", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			benchErr = metaerr.New("failure", metaerr.WithStackTrace(0, 10))
+			_ = benchErr.(metaerr.Error).StackFrames()
+		}
+	})
+	b.Run("printed", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			benchErr = metaerr.New("failure", metaerr.WithStackTrace(0, 10))
+			_ = fmt.Sprintf("%+v", benchErr)
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package metaerr
+
+// ViewOption changes how GetMeta and Render present an error, whatever the
+// metadata definitions say.
+type ViewOption func(*view)
+
+// redactedValue replaces the values of sensitive metadata.
+const redactedValue = "***"
+
+type view struct {
+	order         *MetaOrder
+	unredacted    bool
+	minVisibility Visibility
+	functionNames bool
+}
+
+func newView(opts []ViewOption) view {
+	var v view
+	for _, opt := range opts {
+		opt(&v)
+	}
+	return v
+}
+
+// OverrideOrder orders the values of every metadata with order, instead of the
+// order of their definition (see WithOrder).
+func OverrideOrder(order MetaOrder) ViewOption {
+	return func(v *view) {
+		v.order = &order
+	}
+}
+
+func (v view) orderOf(order MetaOrder) MetaOrder {
+	if v.order != nil {
+		return *v.order
+	}
+	return order
+}
+
+// Unredacted reveals the values of sensitive metadata (see Sensitive), for
+// trusted sinks only.
+func Unredacted() ViewOption {
+	return func(v *view) {
+		v.unredacted = true
+	}
+}
+
+// MinVisibility only keeps the metadata whose visibility is at least
+// visibility (see WithVisibility), e.g. MinVisibility(VisibilityPublic) for
+// the metadata of an API error response.
+func MinVisibility(visibility Visibility) ViewOption {
+	return func(v *view) {
+		v.minVisibility = visibility
+	}
+}
+
+// FunctionNames makes Render show locations and stack frames as
+// "pkg.Func (file:line)", like panics do, instead of "file:line".
+func FunctionNames() ViewOption {
+	return func(v *view) {
+		v.functionNames = true
+	}
+}
@@ -0,0 +1,49 @@
+package metaerr_test
+
+import (
+	"testing"
+
+	"github.com/quantumcycle/metaerr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMinVisibility(t *testing.T) {
+	a := assert.New(t)
+
+	publicCode := metaerr.StringMeta("error_code", metaerr.WithVisibility(metaerr.VisibilityPublic))
+	publicField := metaerr.StringMeta("field", metaerr.WithVisibility(metaerr.VisibilityPublic))
+	shard := metaerr.StringMeta("shard")
+
+	err := metaerr.New("failure", metaerr.WithMeta(publicField("email"), shard("4")))
+	err = metaerr.Wrap(err, "wrapped", metaerr.WithMeta(publicCode("x400")))
+
+	a.Equal(map[string][]string{
+		"error_code": {"x400"},
+		"field":      {"email"},
+	}, metaerr.GetMeta(err, true, metaerr.MinVisibility(metaerr.VisibilityPublic)))
+	a.Equal(map[string][]string{
+		"error_code": {"x400"},
+		"field":      {"email"},
+		"shard":      {"4"},
+	}, metaerr.GetMeta(err, true, metaerr.MinVisibility(metaerr.VisibilityInternal)))
+	a.Equal("wrapped [error_code=x400]: failure [field=email] [shard=4]", err.Error())
+}
+
+func TestJSONRoundTripKeepsVisibility(t *testing.T) {
+	a := assert.New(t)
+
+	tag := metaerr.StringMeta("tag", metaerr.WithVisibility(metaerr.VisibilityPublic))
+	shard := metaerr.StringMeta("shard")
+	err := metaerr.Wrap(metaerr.New("failure", metaerr.WithMeta(tag("t"), shard("4"))), "wrapped", metaerr.WithMeta(shard("5")))
+
+	data, jsonErr := metaerr.MarshalJSON(err)
+	require.NoError(t, jsonErr)
+	decoded, jsonErr := metaerr.UnmarshalJSON(data)
+	require.NoError(t, jsonErr)
+
+	public := metaerr.MinVisibility(metaerr.VisibilityPublic)
+	a.Equal(map[string][]string{"tag": {"t"}}, metaerr.GetMeta(decoded, true, public))
+	a.Equal(metaerr.GetMeta(err, true, public), metaerr.GetMeta(decoded, true, public))
+	a.Equal(metaerr.GetMeta(err, true), metaerr.GetMeta(decoded, true))
+}
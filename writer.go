@@ -1,17 +1,157 @@
 package metaerr
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"sort"
+	"strings"
 )
 
-type errorWriter interface {
+// ErrorWriter receives the layers of an error chain, outermost first. msg is
+// the layer's reason (or the message of a non metaerr error), metadata the
+// layer's formatted metadata ("[k=v] [k2=v1,v2]"). location and stacktrace are
+// empty for non metaerr errors or when they were not captured.
+type ErrorWriter interface {
 	Error(msg, metadata, location string, stacktrace *Stacktrace)
 }
 
+// Renderer creates the ErrorWriter used to render one error to w.
+type Renderer func(w io.Writer) ErrorWriter
+
+// LineRenderer renders the chain on a single line, without locations. This is
+// the format of Error() and of the %s and %v verbs.
+func LineRenderer(w io.Writer) ErrorWriter {
+	return &lineErrorWriter{
+		writer: w,
+	}
+}
+
+// StackRenderer renders each layer of the chain on its own line, followed by
+// its location and stacktrace. This is the format of the %+v verb.
+func StackRenderer(w io.Writer) ErrorWriter {
+	return &stackErrorWriter{
+		writer: w,
+	}
+}
+
+// BranchWriter is implemented by ErrorWriters that lay out the branches of a
+// multi-cause error (errors.Join, fmt.Errorf with several %w) themselves. Each
+// branch has already been rendered with a new ErrorWriter from the same
+// Renderer. ErrorWriters not implementing it receive the branches as a single
+// message, one branch per line.
+type BranchWriter interface {
+	ErrorWriter
+	Branches(branches []string)
+}
+
+// FunctionNameWriter is implemented by ErrorWriters printing stack frames.
+// When Render is given FunctionNames, ShowFunctionNames is called before the
+// first layer, and the frames should then be printed with Frame.FunctionString.
+// Locations are already given in that form.
+type FunctionNameWriter interface {
+	ErrorWriter
+	ShowFunctionNames()
+}
+
+// MessageWriter is implemented by ErrorWriters only printing the messages of
+// the chain, like the one of LineRenderer. The reason of an error created by
+// Newf or Wrapf already holds the messages of its %w operands, so Render does
+// not pass them to a MessageWriter again, except the error given to Wrapf.
+type MessageWriter interface {
+	ErrorWriter
+	MessagesOnly()
+}
+
+// Render writes err to w with the ErrorWriter created by r, walking the chain
+// with errors.Unwrap. Multi-cause errors end the chain with their branches.
+// opts change how metadata is presented, as with GetMeta.
+func Render(w io.Writer, err error, r Renderer, opts ...ViewOption) {
+	v := newView(opts)
+	errWriter := r(w)
+	if fw, ok := errWriter.(FunctionNameWriter); ok && v.functionNames {
+		fw.ShowFunctionNames()
+	}
+	// metadata of metadata-only layers (see Annotate), printed with the next layer
+	var pendingMetas []string
+	for err != nil {
+		var message string = ""
+		var location string = ""
+		var metasStr []string
+		var st *Stacktrace
+
+		causes := unwrapAll(err)
+		if metaError, ok := AsMetaError(err); ok {
+			if _, ok := errWriter.(MessageWriter); ok && metaError.formatted {
+				// the reason already holds the messages of the %w operands
+				causes = nil
+				if metaError.lineCause != nil {
+					causes = []error{metaError.lineCause}
+				}
+			}
+			message = metaError.Reason
+			if len(metaError.Metas) > 0 {
+				metas := GetMeta(metaError, false, opts...)
+				for k, v := range metas {
+					metasStr = append(metasStr, fmt.Sprintf("[%s=%s]", k, strings.Join(v, ",")))
+				}
+			}
+			location = metaError.Location
+			if v.functionNames && location != "" && metaError.Caller.Function != "" {
+				location = metaError.Caller.FunctionString()
+			}
+			st = metaError.Stacktrace
+			if message == "" && location == "" && st == nil && len(causes) == 1 {
+				pendingMetas = append(pendingMetas, metasStr...)
+				err = causes[0]
+				continue
+			}
+		} else if !isJoin(err) {
+			message = err.Error()
+			if _, ok := errWriter.(MessageWriter); ok && len(causes) > 1 && holdsMessages(message, causes) {
+				// as with fmt.Errorf and several %w, the message already holds the causes
+				causes = nil
+			}
+		}
+		errWriter.Error(message, joinMetas(append(metasStr, pendingMetas...)), location, st)
+		pendingMetas = nil
+
+		if len(causes) > 1 {
+			renderBranches(errWriter, causes, r, opts)
+			return
+		}
+		err = nil
+		if len(causes) == 1 {
+			err = causes[0]
+		}
+	}
+}
+
+// joinMetas joins formatted metadata ("[k=v]"), sorted to make the output
+// deterministic.
+func joinMetas(metasStr []string) string {
+	sort.Strings(metasStr)
+	return strings.Join(metasStr, " ")
+}
+
+func renderBranches(errWriter ErrorWriter, causes []error, r Renderer, opts []ViewOption) {
+	branches := make([]string, 0, len(causes))
+	for _, cause := range causes {
+		buf := new(bytes.Buffer)
+		Render(buf, cause, r, opts...)
+		branches = append(branches, buf.String())
+	}
+	if bw, ok := errWriter.(BranchWriter); ok {
+		bw.Branches(branches)
+		return
+	}
+	errWriter.Error(strings.Join(branches, "\n"), "", "", nil)
+}
+
 type stackErrorWriter struct {
 	writer           io.Writer
 	firstLinePrinted bool
+	functionNames    bool
 }
 
 func (ew *stackErrorWriter) Error(msg, metadata, location string, st *Stacktrace) {
@@ -46,7 +186,11 @@ func (ew *stackErrorWriter) Error(msg, metadata, location string, st *Stacktrace
 	if st != nil && len(st.Frames) > 0 {
 		fmt.Fprintf(ew.writer, "\n")
 		for i, frame := range st.Frames {
-			fmt.Fprintf(ew.writer, "\tat %s", frame.String())
+			if ew.functionNames {
+				fmt.Fprintf(ew.writer, "\tat %s", frame.FunctionString())
+			} else {
+				fmt.Fprintf(ew.writer, "\tat %s", frame.String())
+			}
 			if i < len(st.Frames)-1 {
 				fmt.Fprintf(ew.writer, "\n")
 			}
@@ -79,3 +223,49 @@ func (ew *lineErrorWriter) Error(msg, metadata, location string, st *Stacktrace)
 		ew.firstErrorPrinted = true
 	}
 }
+
+// Branches prints each branch as an indented list item.
+func (ew *stackErrorWriter) Branches(branches []string) {
+	for _, branch := range branches {
+		if branch == "" {
+			continue
+		}
+		if ew.firstLinePrinted {
+			fmt.Fprint(ew.writer, "\n")
+		}
+		for i, line := range strings.Split(branch, "\n") {
+			if i == 0 {
+				fmt.Fprintf(ew.writer, "\t- %s", line)
+			} else {
+				fmt.Fprintf(ew.writer, "\n\t  %s", line)
+			}
+		}
+		ew.firstLinePrinted = true
+	}
+}
+
+// ShowFunctionNames prints the function of each stack frame.
+func (ew *stackErrorWriter) ShowFunctionNames() {
+	ew.functionNames = true
+}
+
+// MessagesOnly marks the writer as only printing messages.
+func (ew *lineErrorWriter) MessagesOnly() {}
+
+// Branches prints the branches between brackets, separated by semicolons.
+func (ew *lineErrorWriter) Branches(branches []string) {
+	nonEmpty := make([]string, 0, len(branches))
+	for _, branch := range branches {
+		if branch != "" {
+			nonEmpty = append(nonEmpty, branch)
+		}
+	}
+	if len(nonEmpty) == 0 {
+		return
+	}
+	if ew.firstErrorPrinted {
+		fmt.Fprint(ew.writer, ": ")
+	}
+	fmt.Fprintf(ew.writer, "[%s]", strings.Join(nonEmpty, "; "))
+	ew.firstErrorPrinted = true
+}
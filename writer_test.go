@@ -0,0 +1,136 @@
+package metaerr_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/quantumcycle/metaerr"
+	"github.com/stretchr/testify/assert"
+)
+
+// locationWriter renders each layer as "msg metadata (location)" separated by " | "
+type locationWriter struct {
+	w     io.Writer
+	count int
+}
+
+func (lw *locationWriter) Error(msg, metadata, location string, st *metaerr.Stacktrace) {
+	if lw.count > 0 {
+		fmt.Fprint(lw.w, " | ")
+	}
+	lw.count++
+	fmt.Fprint(lw.w, msg)
+	if metadata != "" {
+		fmt.Fprintf(lw.w, " %s", metadata)
+	}
+	if location != "" {
+		fmt.Fprintf(lw.w, " (%s)", location)
+	}
+}
+
+func TestRenderWithCustomRenderer(t *testing.T) {
+	err := CreateError("failure", map[string][]string{
+		"errorCode": {"code1"},
+	})
+	wrapped := Wrap(err, "wrapped")
+
+	buf := new(bytes.Buffer)
+	metaerr.Render(buf, wrapped, func(w io.Writer) metaerr.ErrorWriter {
+		return &locationWriter{w: w}
+	})
+
+	assert.Regexp(t, fmt.Sprintf(`^wrapped \(.+/metaerr/errors_test.go:%d\) \| failure \[errorCode=code1\] \(.+/metaerr/errors_test.go:%d\)$`,
+		wrapErrorLocation, createErrorLocation), buf.String())
+}
+
+func TestRenderWithBuiltinRenderers(t *testing.T) {
+	a := assert.New(t)
+
+	err := Wrap(CreateError("failure", nil), "wrapped")
+
+	line := new(bytes.Buffer)
+	metaerr.Render(line, err, metaerr.LineRenderer)
+	a.Equal(err.Error(), line.String())
+
+	stack := new(bytes.Buffer)
+	metaerr.Render(stack, err, metaerr.StackRenderer)
+	a.Equal(fmt.Sprintf("%+v", err), stack.String())
+}
+
+func TestRenderNilError(t *testing.T) {
+	buf := new(bytes.Buffer)
+	metaerr.Render(buf, nil, metaerr.StackRenderer)
+	assert.Empty(t, buf.String())
+}
+
+func TestRenderWithFunctionNames(t *testing.T) {
+	a := assert.New(t)
+
+	err := SimulateCreateFromLibraryWithStackLevel2("failure")
+
+	buf := new(bytes.Buffer)
+	metaerr.Render(buf, err, metaerr.StackRenderer, metaerr.FunctionNames())
+
+	a.Regexp(fmt.Sprintf(`^failure\n\tat github.com/quantumcycle/metaerr_test.SimulateCreateFromLibraryWithStack \(.+/metaerr/errors_test.go:%d\)\n\tat github.com/quantumcycle/metaerr_test.SimulateCreateFromLibraryWithStackLevel2 \(.+/metaerr/errors_test.go:%d\)\n`,
+		simulateCreateFromLibraryWithStackLocation, simulateCreateFromLibraryWithStackLevel2Location), buf.String())
+	a.NotContains(fmt.Sprintf("%+v", err), "SimulateCreateFromLibraryWithStack ")
+}
+
+// messageLineWriter renders the messages of the chain separated by " | ", and
+// the function of the first stack frame of each layer when function names are
+// shown.
+type messageLineWriter struct {
+	w             io.Writer
+	count         int
+	functionNames bool
+}
+
+func (mw *messageLineWriter) Error(msg, metadata, location string, st *metaerr.Stacktrace) {
+	if mw.count > 0 {
+		fmt.Fprint(mw.w, " | ")
+	}
+	mw.count++
+	fmt.Fprint(mw.w, msg)
+	if mw.functionNames && st != nil && len(st.Frames) > 0 {
+		fmt.Fprintf(mw.w, " <%s>", st.Frames[0].Function)
+	}
+}
+
+func (mw *messageLineWriter) MessagesOnly() {}
+
+func (mw *messageLineWriter) ShowFunctionNames() {
+	mw.functionNames = true
+}
+
+func messageLineRenderer(w io.Writer) metaerr.ErrorWriter {
+	return &messageLineWriter{w: w}
+}
+
+func TestRenderWithCustomMessageWriter(t *testing.T) {
+	a := assert.New(t)
+	builder := metaerr.NewBuilder()
+	cause := builder.New("not found")
+
+	buf := new(bytes.Buffer)
+	metaerr.Render(buf, builder.Newf("lookup: %w", cause), messageLineRenderer)
+	a.Equal("lookup: not found", buf.String())
+
+	buf.Reset()
+	metaerr.Render(buf, builder.Wrapf(cause, "lookup of %s", "user"), messageLineRenderer)
+	a.Equal("lookup of user | not found", buf.String())
+}
+
+func TestRenderWithCustomFunctionNameWriter(t *testing.T) {
+	a := assert.New(t)
+	err := SimulateCreateFromLibraryWithStackLevel2("failure")
+
+	buf := new(bytes.Buffer)
+	metaerr.Render(buf, err, messageLineRenderer)
+	a.Equal("failure", buf.String())
+
+	buf.Reset()
+	metaerr.Render(buf, err, messageLineRenderer, metaerr.FunctionNames())
+	a.Equal("failure <github.com/quantumcycle/metaerr_test.SimulateCreateFromLibraryWithStackLevel2>", buf.String())
+}